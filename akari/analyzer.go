@@ -11,65 +11,161 @@ type AnalyzeOptions struct {
 	HasPrev bool
 	Prev    io.Reader
 	Logger  DebugLogger
-	Seed    uint64
 }
 
 func Analyze(options AnalyzeOptions) (TableData, error) {
-	parseOptions, err := options.Config.ParseOptions(options.Seed)
+	summary, err := Summarize(options)
 	if err != nil {
-		return TableData{}, fmt.Errorf("Failed to prepare options (%w)", err)
+		return TableData{}, err
 	}
 
-	queryOptions, err := options.Config.QueryOptions()
-	if err != nil {
-		return TableData{}, fmt.Errorf("Failed to prepare query options (%w)", err)
+	return FormatSummary(options.Config, summary, options.Logger)
+}
+
+func (r SummaryRecords) SortKeys(keys []string) ([]SortKey, error) {
+	sortKeys := []SortKey{}
+	for _, key := range keys {
+		sortKey := ParseSortKey(key)
+		sortKey.Index = r.GetIndex(sortKey.Name)
+		if sortKey.Index < 0 {
+			return nil, fmt.Errorf("Unknown sort key: %v", key)
+		}
+
+		sortKeys = append(sortKeys, sortKey)
 	}
 
-	formatOptions, err := options.Config.FormatOptions()
+	return sortKeys, nil
+}
+
+// Summarize parses the logs and summarizes them. The result does not depend on anything but the config and the logs,
+// so it can be stored and formatted later.
+func Summarize(options AnalyzeOptions) (SummaryRecords, error) {
+	parseOptions, err := options.Config.SummaryParseOptions()
 	if err != nil {
-		return TableData{}, fmt.Errorf("Failed to prepare format options (%w)", err)
+		return SummaryRecords{}, err
 	}
 
 	options.Logger.Debug("Loaded options")
 
 	parsed, err := Parse(parseOptions, options.Source, options.Logger)
 	if err != nil {
-		return TableData{}, fmt.Errorf("Failed to parse (%w)", err)
+		return SummaryRecords{}, fmt.Errorf("Failed to parse (%w)", err)
 	}
 
 	options.Logger.Debug("Parsed")
 
-	prevRows := map[string]LogRecordRows{}
+	var prev *LogRecords
 	if options.HasPrev {
-		p, err := Parse(parseOptions, options.Prev, options.Logger)
+		prevParseOptions := parseOptions
+		prevParseOptions.KeepLines = false
+
+		p, err := Parse(prevParseOptions, options.Prev, options.Logger)
 		if err != nil {
-			return TableData{}, fmt.Errorf("Failed to parse previous (%w)", err)
+			return SummaryRecords{}, fmt.Errorf("Failed to parse previous (%w)", err)
 		}
 
-		prevRows = p.Records
+		prev = &p
+	}
+
+	return SummarizeRecords(options.Config, parsed, prev, options.Logger)
+}
+
+// SummaryParseOptions is ParseOptions for Summarize, which keeps the lines if the outliers are picked
+func (config AnalyzerConfig) SummaryParseOptions() (ParseOptions, error) {
+	parseOptions, err := config.ParseOptions()
+	if err != nil {
+		return ParseOptions{}, fmt.Errorf("Failed to prepare options (%w)", err)
+	}
+
+	parseOptions.KeepLines = config.OutliersColumn() != ""
+
+	return parseOptions, nil
+}
+
+// SummarizeRecords summarizes the parsed logs, so that the records parsed once can be summarized again as the log grows.
+// parsed must be parsed with SummaryParseOptions.
+func SummarizeRecords(config AnalyzerConfig, parsed LogRecords, prev *LogRecords, logger DebugLogger) (SummaryRecords, error) {
+	queryOptions, err := config.QueryOptions()
+	if err != nil {
+		return SummaryRecords{}, fmt.Errorf("Failed to prepare query options (%w)", err)
+	}
+
+	prevRows := map[string]LogRecordRows{}
+	if prev != nil {
+		prevRows = prev.Records
 	}
 
 	// summarize
-	summary, err := parsed.Summarize(queryOptions, prevRows)
+	summary, err := parsed.Summarize(queryOptions, prev, config.SignificanceTest())
 	if err != nil {
-		return TableData{}, fmt.Errorf("Failed to summarize (%w)", err)
+		return SummaryRecords{}, fmt.Errorf("Failed to summarize (%w)", err)
+	}
+
+	logger.Debug("Summarized")
+
+	// groups beyond the limit are folded into one row, which needs the records to compute
+	otherKeys := []string{}
+	limit := config.Limit
+	if limit > 0 && len(summary.Rows) > limit {
+		sortKeys, err := summary.SortKeys(config.SortKeys)
+		if err != nil {
+			return SummaryRecords{}, err
+		}
+
+		records := summary.GetKeyPairs()
+		records.SortBy(SortByOptions{
+			SortKeys: sortKeys,
+		})
+
+		rest := SummaryRecordKeyPairs{Entries: records.Entries[limit:]}
+		otherKeys = rest.Keys()
+		other, err := parsed.SummarizeOther(queryOptions, otherKeys, prevRows, config.SignificanceTest())
+		if err != nil {
+			return SummaryRecords{}, fmt.Errorf("Failed to summarize other groups (%w)", err)
+		}
+
+		summary.Other = other
+
+		logger.Debug("Summarized other groups")
 	}
 
-	options.Logger.Debug("Summarized")
+	if outliersColumn := config.OutliersColumn(); outliersColumn != "" {
+		outliers, err := parsed.Outliers(outliersColumn, config.Outliers.Count)
+		if err != nil {
+			return SummaryRecords{}, fmt.Errorf("Failed to pick outliers (%w)", err)
+		}
+		if len(otherKeys) > 0 {
+			outliers.Fold(otherKeys, config.Outliers.Count)
+		}
+
+		summary.Outliers = outliers
+
+		logger.Debug("Picked outliers")
+	}
+
+	return summary, nil
+}
+
+// FormatSummary sorts the summary and formats it into the table
+func FormatSummary(config AnalyzerConfig, summary SummaryRecords, logger DebugLogger) (TableData, error) {
+	formatOptions, err := config.FormatOptions()
+	if err != nil {
+		return TableData{}, fmt.Errorf("Failed to prepare format options (%w)", err)
+	}
 
 	records := summary.GetKeyPairs()
 
-	orderKeyIndexes := []int{}
-	for _, orderKey := range options.Config.SortKeys {
-		orderKeyIndexes = append(orderKeyIndexes, summary.GetIndex(orderKey))
+	sortKeys, err := summary.SortKeys(config.SortKeys)
+	if err != nil {
+		return TableData{}, err
 	}
 
 	// sort
 	prevRanks := map[string]int{}
-	if options.Config.ShowRank && options.HasPrev {
+	if config.ShowRank && summary.HasPrev {
 		records.SortBy(SortByOptions{
-			SortKeyIndexes: orderKeyIndexes,
-			UsePrev:        true,
+			SortKeys: sortKeys,
+			UsePrev:  true,
 		})
 
 		for i, record := range records.Entries {
@@ -78,17 +174,29 @@ func Analyze(options AnalyzeOptions) (TableData, error) {
 	}
 
 	records.SortBy(SortByOptions{
-		SortKeyIndexes: orderKeyIndexes,
+		SortKeys: sortKeys,
+	})
+
+	gone := summary.GetGoneKeyPairs()
+	gone.SortBy(SortByOptions{
+		SortKeys: sortKeys,
+		UsePrev:  true,
 	})
 
-	options.Logger.Debug("Sorted")
+	logger.Debug("Sorted")
+
+	if summary.Other != nil && config.Limit > 0 && len(records.Entries) > config.Limit {
+		records.Fold(config.Limit, summary.Other)
+	}
 
 	// format
-	formatOptions.AddRank = options.Config.ShowRank
+	formatOptions.AddRank = config.ShowRank
 	formatOptions.PrevRanks = prevRanks
 	result := records.Format(formatOptions)
+	result.Gone = gone.Format(formatOptions).Rows
+	result.Outliers = summary.Outliers
 
-	options.Logger.Debug("Formatted")
+	logger.Debug("Formatted")
 
 	return result, nil
 }
@@ -0,0 +1,195 @@
+package akari
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testAnalyzerConfig() AnalyzerConfig {
+	return AnalyzerConfig{
+		Name: "test",
+		Parser: ParserConfig{
+			RegExp: regexp.MustCompile(`^(?P<Method>\S+) (?P<Url>\S+) (?P<Time>\S+)$`),
+			Columns: ParserColumnConfigs{
+				{Name: "Method"},
+				{Name: "Url"},
+				{Name: "Time", Converters: []ParserColumnConverterConfig{{Type: "parseFloat64"}}},
+			},
+		},
+		GroupingKeys: []string{"Method", "Url"},
+		Query: []QueryConfig{
+			{
+				From: "Time",
+				Columns: []QueryConfig{
+					{Name: ptr("Count"), Function: QueryFunctionCount},
+					{Name: ptr("Total"), Function: QueryFunctionSum},
+				},
+			},
+			{From: "Method"},
+			{From: "Url"},
+		},
+		SortKeys: []string{"Total"},
+	}
+}
+
+func testAnalyze(t *testing.T, config AnalyzerConfig, source string, prev string) TableData {
+	t.Helper()
+
+	result, err := Analyze(AnalyzeOptions{
+		Config:  config,
+		Source:  strings.NewReader(source),
+		HasPrev: prev != "",
+		Prev:    strings.NewReader(prev),
+		Logger:  slog.Default(),
+	})
+	if err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+
+	return result
+}
+
+func TestAnalyzeFoldsGroupsBeyondLimit(t *testing.T) {
+	config := testAnalyzerConfig()
+	config.Limit = 2
+
+	result := testAnalyze(t, config, `GET /a 4.0
+GET /b 3.0
+GET /c 2.0
+GET /d 0.5
+GET /d 0.5
+`, "")
+
+	assert.Len(t, result.Rows, 3)
+	assert.Equal(t, []string{"1", "4.000", "GET", "/a"}, cellValues(result.Rows[0]))
+	assert.Equal(t, []string{"3", "3.000", "", OtherGroupLabel(2)}, cellValues(result.Rows[2]))
+	assert.Equal(t, OtherGroupKey, result.Rows[2].Key)
+}
+
+func TestAnalyzeOutliers(t *testing.T) {
+	config := testAnalyzerConfig()
+	config.Outliers = &OutliersConfig{Count: 2, Column: "Time"}
+
+	result := testAnalyze(t, config, `GET /a 1.0
+GET /a 3.0
+GET /b 0.5
+GET /a 2.0
+`, "GET /a 9.0\n")
+
+	key, err := GroupKey([]any{"GET", "/a"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Time", result.Outliers.Column)
+	assert.Equal(t, []Outlier{
+		{Line: LogLine{Number: 2, Text: "GET /a 3.0"}, Value: 3},
+		{Line: LogLine{Number: 4, Text: "GET /a 2.0"}, Value: 2},
+	}, result.Outliers.Rows[key])
+
+	// NaN and Inf are not the largest values
+	result = testAnalyze(t, config, "GET /a +Inf\nGET /a NaN\nGET /a 1.0\n", "")
+	assert.Equal(t, []Outlier{
+		{Line: LogLine{Number: 3, Text: "GET /a 1.0"}, Value: 1},
+	}, result.Outliers.Rows[key])
+
+	// the groups folded into the other row share the outliers
+	config.Limit = 1
+	result = testAnalyze(t, config, `GET /a 9.0
+GET /b 0.5
+GET /c 2.0
+GET /b 1.0
+GET /c 0.1
+`, "")
+
+	assert.Equal(t, []Outlier{
+		{Line: LogLine{Number: 3, Text: "GET /c 2.0"}, Value: 2},
+		{Line: LogLine{Number: 4, Text: "GET /b 1.0"}, Value: 1},
+	}, result.Outliers.Rows[OtherGroupKey])
+
+	buffer := bytes.NewBuffer(nil)
+	result.WriteOutliers(buffer)
+	assert.Equal(t, `
+Outliers by Time
+
+GET /a
+       9.000    L1  GET /a 9.0
+
+(other)
+       2.000    L3  GET /c 2.0
+       1.000    L4  GET /b 1.0
+`, buffer.String())
+
+	config.Outliers.Column = "Method"
+	_, err = Analyze(AnalyzeOptions{Config: config, Source: strings.NewReader("GET /a 1.0\n"), Logger: slog.Default()})
+	assert.Error(t, err)
+}
+
+func cellValues(row TableRow) []string {
+	values := []string{}
+	for _, cell := range row.Cells {
+		values = append(values, cell.Value)
+	}
+
+	return values
+}
+
+func TestAnalyzeSortKeys(t *testing.T) {
+	source := `GET /b 1.0
+GET /a 1.0
+GET /c 3.0
+`
+
+	config := testAnalyzerConfig()
+	config.SortKeys = []string{"-Total", "+Url"}
+	result := testAnalyze(t, config, source, "")
+	assert.Equal(t, []string{"/c", "/a", "/b"}, columnValues(result, 3))
+
+	config.SortKeys = []string{"Url"}
+	result = testAnalyze(t, config, source, "")
+	assert.Equal(t, []string{"/c", "/b", "/a"}, columnValues(result, 3))
+
+	config.SortKeys = []string{"-diff:Total", "+Url"}
+	result = testAnalyze(t, config, source, `GET /a 2.0
+GET /b 0.5
+GET /c 3.0
+`)
+	assert.Equal(t, []string{"/b", "/c", "/a"}, columnValues(result, 3))
+}
+
+func columnValues(data TableData, index int) []string {
+	values := []string{}
+	for _, row := range data.Rows {
+		values = append(values, row.Cells[index].Value)
+	}
+
+	return values
+}
+
+func TestAnalyzeNewAndGoneGroups(t *testing.T) {
+	result := testAnalyze(t, testAnalyzerConfig(), `GET /a 1.0
+GET /new 2.0
+`, `GET /a 1.0
+GET /gone 3.0
+`)
+
+	assert.Equal(t, []string{"/new", "/a"}, columnValues(result, 3))
+	assert.Equal(t, GroupStatusNew, result.Rows[0].Status)
+	assert.Equal(t, GroupStatus(""), result.Rows[1].Status)
+
+	assert.Len(t, result.Gone, 1)
+	assert.Equal(t, GroupStatusGone, result.Gone[0].Status)
+	assert.Equal(t, []string{"1", "3.000", "GET", "/gone"}, cellValues(result.Gone[0]))
+	assert.Equal(t, []any{"GET", "/gone"}, result.Gone[0].Group)
+
+	result = testAnalyze(t, testAnalyzerConfig(), "GET /a 1.0\n", "")
+	assert.Equal(t, GroupStatus(""), result.Rows[0].Status)
+	assert.Empty(t, result.Gone)
+}
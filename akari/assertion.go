@@ -0,0 +1,220 @@
+package akari
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type AssertionOperator string
+
+const (
+	AssertionOperatorGreaterThan      AssertionOperator = ">"
+	AssertionOperatorGreaterThanEqual AssertionOperator = ">="
+	AssertionOperatorLessThan         AssertionOperator = "<"
+	AssertionOperatorLessThanEqual    AssertionOperator = "<="
+	AssertionOperatorEqual            AssertionOperator = "=="
+	AssertionOperatorNotEqual         AssertionOperator = "!="
+)
+
+func (o AssertionOperator) Compare(value float64, threshold float64) (bool, error) {
+	switch o {
+	case AssertionOperatorGreaterThan:
+		return value > threshold, nil
+	case AssertionOperatorGreaterThanEqual:
+		return value >= threshold, nil
+	case AssertionOperatorLessThan:
+		return value < threshold, nil
+	case AssertionOperatorLessThanEqual:
+		return value <= threshold, nil
+	case AssertionOperatorEqual:
+		return value == threshold, nil
+	case AssertionOperatorNotEqual:
+		return value != threshold, nil
+	default:
+		return false, fmt.Errorf("Unknown operator: %v", o)
+	}
+}
+
+// Assertion is a rule that every row must not match, like "5xx > 0" or "P95 diff > 20%"
+type Assertion struct {
+	Rule      string
+	Column    string
+	Diff      bool
+	Operator  AssertionOperator
+	Threshold float64
+}
+
+var assertionRegExp = regexp.MustCompile(`^\s*(\S+?)(\s+diff)?\s*(>=|<=|==|!=|>|<)\s*(\S+?)(%?)\s*$`)
+
+func ParseAssertion(rule string) (Assertion, error) {
+	matches := assertionRegExp.FindStringSubmatch(rule)
+	if matches == nil {
+		return Assertion{}, fmt.Errorf("Invalid assertion: %v", rule)
+	}
+
+	threshold, err := strconv.ParseFloat(matches[4], 64)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("Invalid threshold in assertion: %v (%w)", rule, err)
+	}
+	if matches[5] == "%" {
+		threshold /= 100
+	}
+
+	return Assertion{
+		Rule:      strings.TrimSpace(rule),
+		Column:    matches[1],
+		Diff:      matches[2] != "",
+		Operator:  AssertionOperator(matches[3]),
+		Threshold: threshold,
+	}, nil
+}
+
+func ParseAssertions(rules []string) ([]Assertion, error) {
+	assertions := []Assertion{}
+	for _, rule := range rules {
+		assertion, err := ParseAssertion(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		assertions = append(assertions, assertion)
+	}
+
+	return assertions, nil
+}
+
+// NumericValue reads the value of a numeric column as float64
+func NumericValue(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+type AssertionViolation struct {
+	Assertion Assertion
+	Row       TableRow
+	Cell      TableCell
+	Value     float64
+}
+
+func (d TableData) columnIndex(name string) int {
+	for i, column := range d.Columns {
+		if column.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// assertionDiff is the relative change from prevValue to value.
+// Unlike RelativeDiff, small previous values are not ignored, and an increase from 0 is +Inf.
+func assertionDiff(value any, prevValue any) (float64, bool) {
+	v, ok := NumericValue(value)
+	if !ok {
+		return 0, false
+	}
+	p, ok := NumericValue(prevValue)
+	if !ok {
+		return 0, false
+	}
+
+	if p == 0 {
+		switch {
+		case v > 0:
+			return math.Inf(1), true
+		case v < 0:
+			return math.Inf(-1), true
+		default:
+			return 0, true
+		}
+	}
+
+	return (v - p) / math.Abs(p), true
+}
+
+// Check returns the rows violating the assertions.
+// Diff assertions skip the rows without the previous value.
+func (d TableData) Check(assertions []Assertion) ([]AssertionViolation, error) {
+	violations := []AssertionViolation{}
+	for _, assertion := range assertions {
+		index := d.columnIndex(assertion.Column)
+		if index < 0 {
+			return nil, fmt.Errorf("Unknown column in assertion: %v", assertion.Rule)
+		}
+
+		for _, row := range d.Rows {
+			cell := row.Cells[index]
+
+			value, ok := NumericValue(cell.RawValue)
+			if !ok {
+				return nil, fmt.Errorf("Column is not numeric in assertion: %v", assertion.Rule)
+			}
+			if assertion.Diff {
+				if cell.PrevRawValue == nil {
+					continue
+				}
+
+				value, ok = assertionDiff(cell.RawValue, cell.PrevRawValue)
+				if !ok {
+					return nil, fmt.Errorf("Column is not numeric in assertion: %v", assertion.Rule)
+				}
+			}
+
+			violated, err := assertion.Operator.Compare(value, assertion.Threshold)
+			if err != nil {
+				return nil, err
+			}
+
+			if violated {
+				violations = append(violations, AssertionViolation{
+					Assertion: assertion,
+					Row:       row,
+					Cell:      cell,
+					Value:     value,
+				})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+func (r TableRow) Label() string {
+	if len(r.Group) == 0 {
+		return r.Key
+	}
+
+	values := []string{}
+	for _, value := range r.Group {
+		values = append(values, fmt.Sprintf("%v", value))
+	}
+
+	return strings.Join(values, " ")
+}
+
+func WriteViolations(w io.Writer, violations []AssertionViolation) {
+	for _, violation := range violations {
+		detail := fmt.Sprintf("%v=%v", violation.Assertion.Column, strings.TrimSpace(violation.Cell.Value))
+		if violation.Assertion.Diff {
+			if math.IsInf(violation.Value, 0) {
+				detail = fmt.Sprintf("%v (from %v)", detail, violation.Cell.PrevRawValue)
+			} else {
+				detail = fmt.Sprintf("%v (%+.1f%%)", detail, violation.Value*100)
+			}
+		}
+
+		fmt.Fprintf(w, "FAIL [%v] %v: %v\n", violation.Assertion.Rule, violation.Row.Label(), detail)
+	}
+}
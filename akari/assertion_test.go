@@ -0,0 +1,89 @@
+package akari
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAssertion(t *testing.T) {
+	assertion, err := ParseAssertion("P95 diff > 20%")
+	assert.NoError(t, err)
+	assert.Equal(t, Assertion{
+		Rule:      "P95 diff > 20%",
+		Column:    "P95",
+		Diff:      true,
+		Operator:  AssertionOperatorGreaterThan,
+		Threshold: 0.2,
+	}, assertion)
+
+	assertion, err = ParseAssertion("5xx>=1")
+	assert.NoError(t, err)
+	assert.Equal(t, "5xx", assertion.Column)
+	assert.False(t, assertion.Diff)
+	assert.Equal(t, AssertionOperatorGreaterThanEqual, assertion.Operator)
+	assert.Equal(t, 1.0, assertion.Threshold)
+
+	_, err = ParseAssertion("P95 is slow")
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	data := testTableData()
+
+	assertions, err := ParseAssertions([]string{"Count > 1", "Mean diff < -10%"})
+	assert.NoError(t, err)
+
+	violations, err := data.Check(assertions)
+	assert.NoError(t, err)
+	assert.Len(t, violations, 2)
+	assert.Equal(t, `["/a|b"]`, violations[0].Row.Key)
+	assert.Equal(t, "Mean", violations[1].Assertion.Column)
+	assert.Equal(t, -0.5, violations[1].Value)
+
+	_, err = data.Check([]Assertion{{Rule: "Unknown > 0", Column: "Unknown"}})
+	assert.Error(t, err)
+}
+
+func TestCheckDiffFromSmallValues(t *testing.T) {
+	data := TableData{
+		Columns: []TableColumn{
+			{Name: "P95", Type: LogRecordTypeFloat64, Alignment: TableColumnAlignmentRight},
+			{Name: "5xx", Type: LogRecordTypeInt, Alignment: TableColumnAlignmentRight},
+		},
+		Rows: []TableRow{
+			{Key: `["/a"]`, Cells: []TableCell{
+				{Value: "0.060", RawValue: 0.06, PrevRawValue: 0.04},
+				{Value: "3", RawValue: 3, PrevRawValue: 0},
+			}},
+			{Key: `["/b"]`, Cells: []TableCell{
+				{Value: "0.041", RawValue: 0.041, PrevRawValue: 0.04},
+				{Value: "0", RawValue: 0, PrevRawValue: 0},
+			}},
+		},
+	}
+
+	// the baseline is under 100ms
+	assertions, err := ParseAssertions([]string{"P95 diff > 20%"})
+	assert.NoError(t, err)
+	violations, err := data.Check(assertions)
+	assert.NoError(t, err)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, `["/a"]`, violations[0].Row.Key)
+	assert.InDelta(t, 0.5, violations[0].Value, 1e-9)
+
+	// the baseline is 0
+	assertions, err = ParseAssertions([]string{"5xx diff > 0"})
+	assert.NoError(t, err)
+	violations, err = data.Check(assertions)
+	assert.NoError(t, err)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, `["/a"]`, violations[0].Row.Key)
+	assert.True(t, math.IsInf(violations[0].Value, 1))
+
+	buf := bytes.NewBuffer(nil)
+	WriteViolations(buf, violations)
+	assert.Equal(t, "FAIL [5xx diff > 0] [\"/a\"]: 5xx=3 (from 0)\n", buf.String())
+}
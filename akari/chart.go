@@ -0,0 +1,271 @@
+package akari
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ChartPoint struct {
+	Label string
+	Value float64
+}
+
+type ChartOptions struct {
+	Title  string
+	Width  int
+	Height int
+}
+
+const (
+	chartPaddingLeft   = 56
+	chartPaddingRight  = 16
+	chartPaddingTop    = 24
+	chartPaddingBottom = 24
+	chartTicks         = 4
+)
+
+// LineChart renders the points as an inline SVG. The y axis starts from zero unless there are negative values.
+func LineChart(points []ChartPoint, options ChartOptions) template.HTML {
+	width := float64(options.Width)
+	height := float64(options.Height)
+	plotWidth := width - chartPaddingLeft - chartPaddingRight
+	plotHeight := height - chartPaddingTop - chartPaddingBottom
+
+	yMin, yMax := 0.0, 0.0
+	for _, point := range points {
+		yMin = math.Min(yMin, point.Value)
+		yMax = math.Max(yMax, point.Value)
+	}
+	if yMax == yMin {
+		yMax = yMin + 1
+	}
+
+	x := func(i int) float64 {
+		if len(points) == 1 {
+			return chartPaddingLeft + plotWidth/2
+		}
+
+		return chartPaddingLeft + plotWidth*float64(i)/float64(len(points)-1)
+	}
+	y := func(value float64) float64 {
+		return chartPaddingTop + plotHeight*(yMax-value)/(yMax-yMin)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, options.Width, options.Height, options.Width, options.Height)
+	fmt.Fprintf(&b, `<text class="chart-title" x="%d" y="16">%s</text>`, chartPaddingLeft, template.HTMLEscapeString(options.Title))
+
+	for i := 0; i <= chartTicks; i++ {
+		value := yMin + (yMax-yMin)*float64(i)/chartTicks
+		fmt.Fprintf(&b, `<line class="chart-grid" x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" />`, chartPaddingLeft, y(value), width-chartPaddingRight, y(value))
+		fmt.Fprintf(&b, `<text class="chart-tick" x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%.4g</text>`, chartPaddingLeft-4, y(value), value)
+	}
+
+	if len(points) > 0 {
+		coords := []string{}
+		for i, point := range points {
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", x(i), y(point.Value)))
+		}
+		fmt.Fprintf(&b, `<polyline class="chart-line" points="%s" />`, strings.Join(coords, " "))
+
+		for i, point := range points {
+			fmt.Fprintf(&b, `<circle class="chart-point" cx="%.1f" cy="%.1f" r="3"><title>%s: %.4g</title></circle>`, x(i), y(point.Value), template.HTMLEscapeString(point.Label), point.Value)
+		}
+
+		// only the first and the last labels to avoid overlapping
+		fmt.Fprintf(&b, `<text class="chart-tick" x="%d" y="%.1f" text-anchor="start">%s</text>`, chartPaddingLeft, height-6, template.HTMLEscapeString(points[0].Label))
+		if len(points) > 1 {
+			fmt.Fprintf(&b, `<text class="chart-tick" x="%.1f" y="%.1f" text-anchor="end">%s</text>`, width-chartPaddingRight, height-6, template.HTMLEscapeString(points[len(points)-1].Label))
+		}
+	}
+
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+// BarChart renders the points as bars of an inline SVG, from zero
+func BarChart(points []ChartPoint, options ChartOptions) template.HTML {
+	width := float64(options.Width)
+	height := float64(options.Height)
+	plotWidth := width - chartPaddingLeft - chartPaddingRight
+	plotHeight := height - chartPaddingTop - chartPaddingBottom
+
+	yMax := 0.0
+	for _, point := range points {
+		yMax = math.Max(yMax, point.Value)
+	}
+	if yMax == 0 {
+		yMax = 1
+	}
+
+	y := func(value float64) float64 {
+		return chartPaddingTop + plotHeight*(yMax-value)/yMax
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, options.Width, options.Height, options.Width, options.Height)
+	fmt.Fprintf(&b, `<text class="chart-title" x="%d" y="16">%s</text>`, chartPaddingLeft, template.HTMLEscapeString(options.Title))
+
+	for i := 0; i <= chartTicks; i++ {
+		value := yMax * float64(i) / chartTicks
+		fmt.Fprintf(&b, `<line class="chart-grid" x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" />`, chartPaddingLeft, y(value), width-chartPaddingRight, y(value))
+		fmt.Fprintf(&b, `<text class="chart-tick" x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%.4g</text>`, chartPaddingLeft-4, y(value), value)
+	}
+
+	if len(points) > 0 {
+		barWidth := plotWidth / float64(len(points))
+		for i, point := range points {
+			fmt.Fprintf(&b, `<rect class="chart-bar" x="%.1f" y="%.1f" width="%.1f" height="%.1f"><title>%s: %.4g</title></rect>`, chartPaddingLeft+barWidth*float64(i)+0.5, y(point.Value), math.Max(barWidth-1, 1), y(0)-y(point.Value), template.HTMLEscapeString(point.Label), point.Value)
+		}
+
+		fmt.Fprintf(&b, `<text class="chart-tick" x="%d" y="%.1f" text-anchor="start">%s</text>`, chartPaddingLeft, height-6, template.HTMLEscapeString(points[0].Label))
+		if len(points) > 1 {
+			fmt.Fprintf(&b, `<text class="chart-tick" x="%.1f" y="%.1f" text-anchor="end">%s</text>`, width-chartPaddingRight, height-6, template.HTMLEscapeString(points[len(points)-1].Label))
+		}
+	}
+
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+type HistogramBin struct {
+	Min   float64
+	Max   float64
+	Count int
+}
+
+// Histogram splits the range of the values into the bins of the same width. NaN and Inf are skipped.
+func Histogram(values []float64, bins int) []HistogramBin {
+	values = slices.DeleteFunc(slices.Clone(values), func(value float64) bool {
+		return math.IsNaN(value) || math.IsInf(value, 0)
+	})
+	if len(values) == 0 || bins <= 0 {
+		return nil
+	}
+
+	minValue, maxValue := slices.Min(values), slices.Max(values)
+	if minValue == maxValue {
+		return []HistogramBin{{Min: minValue, Max: maxValue, Count: len(values)}}
+	}
+
+	width := (maxValue - minValue) / float64(bins)
+	result := []HistogramBin{}
+	for i := 0; i < bins; i++ {
+		result = append(result, HistogramBin{
+			Min: minValue + width*float64(i),
+			Max: minValue + width*float64(i+1),
+		})
+	}
+
+	for _, value := range values {
+		// the max value falls into the last bin
+		index := min(int((value-minValue)/width), bins-1)
+		result[index].Count++
+	}
+
+	return result
+}
+
+// HistogramPoints labels each bin by its range
+func HistogramPoints(bins []HistogramBin) []ChartPoint {
+	points := []ChartPoint{}
+	for _, bin := range bins {
+		points = append(points, ChartPoint{
+			Label: fmt.Sprintf("%.4g-%.4g", bin.Min, bin.Max),
+			Value: float64(bin.Count),
+		})
+	}
+
+	return points
+}
+
+// CumulativePoints returns the ratio of the values up to the upper bound of each bin
+func CumulativePoints(bins []HistogramBin) []ChartPoint {
+	total := 0
+	for _, bin := range bins {
+		total += bin.Count
+	}
+
+	points := []ChartPoint{}
+	count := 0
+	for _, bin := range bins {
+		count += bin.Count
+		points = append(points, ChartPoint{
+			Label: fmt.Sprintf("%.4g", bin.Max),
+			Value: float64(count) / float64(total),
+		})
+	}
+
+	return points
+}
+
+var timestampLayouts = []string{
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// TimestampValue reads the value of a timestamp column, which is a datetime or a string in the nginx or the ISO 8601 format
+func TimestampValue(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+type TimeBucket struct {
+	Start  time.Time
+	Values []float64
+}
+
+var timelineIntervals = []time.Duration{
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// Timeline groups the values by the intervals of their times. The interval is the smallest one which makes at most maxBuckets buckets.
+// Intervals without values are kept as empty buckets.
+func Timeline(times []time.Time, values []float64, maxBuckets int) ([]TimeBucket, time.Duration) {
+	if len(times) == 0 {
+		return nil, 0
+	}
+
+	first, last := slices.MinFunc(times, time.Time.Compare), slices.MaxFunc(times, time.Time.Compare)
+
+	interval := timelineIntervals[len(timelineIntervals)-1]
+	for _, candidate := range timelineIntervals {
+		if int(last.Sub(first.Truncate(candidate))/candidate)+1 <= maxBuckets {
+			interval = candidate
+			break
+		}
+	}
+
+	start := first.Truncate(interval)
+	buckets := []TimeBucket{}
+	for i := 0; i <= int(last.Sub(start)/interval); i++ {
+		buckets = append(buckets, TimeBucket{Start: start.Add(interval * time.Duration(i))})
+	}
+
+	for i, t := range times {
+		index := int(t.Sub(start) / interval)
+		buckets[index].Values = append(buckets[index].Values, values[i])
+	}
+
+	return buckets, interval
+}
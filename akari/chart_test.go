@@ -0,0 +1,39 @@
+package akari
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	bins := Histogram([]float64{0, 1, 2, 3, 4}, 2)
+	assert.Equal(t, []HistogramBin{{Min: 0, Max: 2, Count: 2}, {Min: 2, Max: 4, Count: 3}}, bins)
+	assert.Equal(t, []ChartPoint{{Label: "2", Value: 0.4}, {Label: "4", Value: 1}}, CumulativePoints(bins))
+
+	assert.Equal(t, []HistogramBin{{Min: 1, Max: 1, Count: 2}}, Histogram([]float64{1, 1}, 2))
+	assert.Empty(t, Histogram(nil, 2))
+
+	// NaN and Inf have no bin
+	bins = Histogram([]float64{math.NaN(), 0, 4, math.Inf(1), math.Inf(-1)}, 2)
+	assert.Equal(t, []HistogramBin{{Min: 0, Max: 2, Count: 1}, {Min: 2, Max: 4, Count: 1}}, bins)
+	assert.Empty(t, Histogram([]float64{math.NaN()}, 2))
+}
+
+func TestTimeline(t *testing.T) {
+	start, ok := TimestampValue("03/Jan/2025:13:29:00 +0900")
+	assert.True(t, ok)
+
+	times := []time.Time{start, start.Add(5 * time.Second), start.Add(25 * time.Second)}
+	buckets, interval := Timeline(times, []float64{1, 2, 3}, 3)
+	assert.Equal(t, 10*time.Second, interval)
+	assert.Len(t, buckets, 3)
+	assert.Equal(t, []float64{1, 2}, buckets[0].Values)
+	assert.Empty(t, buckets[1].Values)
+	assert.Equal(t, []float64{3}, buckets[2].Values)
+
+	_, ok = TimestampValue("yesterday")
+	assert.False(t, ok)
+}
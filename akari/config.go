@@ -1,8 +1,10 @@
 package akari
 
 import (
+	"cmp"
 	"fmt"
 	"regexp"
+	"time"
 )
 
 type ParserColumnRegExpSpecifier struct {
@@ -154,6 +156,18 @@ type AddColumnConfig struct {
 	FormatOption QueryFormatConfig
 }
 
+type SignificanceConfig struct {
+	Test  SignificanceTest
+	Alpha float64
+}
+
+type OutliersConfig struct {
+	// records to keep for each group
+	Count int
+	// defaults to the latency column
+	Column string
+}
+
 type AnalyzerConfig struct {
 	Name         string
 	Parser       ParserConfig
@@ -162,20 +176,74 @@ type AnalyzerConfig struct {
 	SortKeys     []string
 	Limit        int
 	Diffs        []string
-	ShowRank     bool
+	// diff columns whose increase is an improvement, e.g. Count
+	HigherIsBetter []string
+	ShowRank       bool
+	Assertions     []string
+	Significance   *SignificanceConfig
+	Outliers       *OutliersConfig
+	// the unit of LatencyColumn as a duration, e.g. "1ms" (default "1s")
+	LatencyUnit string
+}
+
+func (config AnalyzerConfig) SignificanceTest() SignificanceTest {
+	if config.Significance == nil {
+		return SignificanceTestNone
+	}
+
+	return cmp.Or(config.Significance.Test, SignificanceTestMannWhitneyU)
+}
+
+// LatencyColumn returns the column of the first query which takes statistics of it, which is the response time in most configurations
+func (config AnalyzerConfig) LatencyColumn() string {
+	for _, query := range config.Query {
+		if query.Function.IsStatistic() {
+			return query.From
+		}
+
+		for _, column := range query.Columns {
+			if column.Function.IsStatistic() {
+				return query.From
+			}
+		}
+	}
+
+	return ""
 }
 
-func (config AnalyzerConfig) ParseOptions(seed uint64) (ParseOptions, error) {
+// LatencyDuration returns the unit of LatencyColumn
+func (config AnalyzerConfig) LatencyDuration() (time.Duration, error) {
+	if config.LatencyUnit == "" {
+		return time.Second, nil
+	}
+
+	unit, err := time.ParseDuration(config.LatencyUnit)
+	if err != nil || unit <= 0 {
+		return 0, fmt.Errorf("Invalid latencyUnit: %v", config.LatencyUnit)
+	}
+
+	return unit, nil
+}
+
+// OutliersColumn returns the column to pick the outliers by, or empty if outliers are not enabled
+func (config AnalyzerConfig) OutliersColumn() string {
+	if config.Outliers == nil || config.Outliers.Count <= 0 {
+		return ""
+	}
+
+	return cmp.Or(config.Outliers.Column, config.LatencyColumn())
+}
+
+func (config AnalyzerConfig) ParseOptions() (ParseOptions, error) {
 	columns, err := config.Parser.Columns.Load()
 	if err != nil {
 		return ParseOptions{}, fmt.Errorf("Failed to load columns (%w)", err)
 	}
 
 	parseOptions := ParseOptions{
-		RegExp:   config.Parser.RegExp,
-		Columns:  columns,
-		Keys:     config.GroupingKeys,
-		HashSeed: seed,
+		RegExp:  config.Parser.RegExp,
+		Columns: columns,
+		Keys:    config.GroupingKeys,
 	}
 	return parseOptions, nil
 }
@@ -271,14 +339,47 @@ func (config AnalyzerConfig) FormatOptions() (FormatOptions, error) {
 		}
 	}
 
+	alpha := 0.05
+	if config.Significance != nil && config.Significance.Alpha > 0 {
+		alpha = config.Significance.Alpha
+	}
+
 	return FormatOptions{
 		ColumnOptions: columns,
-		Limit:         config.Limit,
+		Alpha:         alpha,
 	}, nil
 }
 
+type ServerAuthConfig struct {
+	// basic auth
+	Username string
+	Password string
+	// bearer token which can access everything
+	Token string
+	// bearer token which can only read the JSON API
+	ReadOnlyToken string
+}
+
+// Enabled reports whether any of the credentials is set. A password alone enables it too, so that a mistake fails closed.
+func (c ServerAuthConfig) Enabled() bool {
+	return c.Username != "" || c.Password != "" || c.Token != "" || c.ReadOnlyToken != ""
+}
+
+func (c ServerAuthConfig) Validate() error {
+	if c.Password != "" && c.Username == "" {
+		return fmt.Errorf("Password is set without username")
+	}
+
+	return nil
+}
+
+type ServerConfig struct {
+	Auth ServerAuthConfig
+}
+
 type AkariConfig struct {
 	Analyzers []AnalyzerConfig
+	Server    ServerConfig
 }
 
 func (c AkariConfig) GetLogTypes() []string {
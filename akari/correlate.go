@@ -0,0 +1,186 @@
+package akari
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+type CorrelateLogsOptions struct {
+	Source LogRecords
+	// the group of the source records
+	Key string
+	// the duration of the source records, which ends at their timestamps (empty for the timestamps only)
+	DurationColumn string
+	// the unit of DurationColumn (zero for seconds)
+	DurationUnit time.Duration
+	Target       LogRecords
+	// the value of the target records to sum up, e.g. the query time (optional)
+	ValueColumn string
+	// matches the records by the column which both logs have, instead of the time windows
+	JoinColumn string
+	// widens the windows, for the timestamps logged in seconds
+	Margin time.Duration
+}
+
+type CorrelatedGroup struct {
+	Key   string
+	Group []any
+	// the target records in the windows of the source group
+	Count int
+	Total float64
+}
+
+type timeWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// mergeWindows sorts the windows and merges the overlapping ones
+func mergeWindows(windows []timeWindow) []timeWindow {
+	slices.SortFunc(windows, func(a, b timeWindow) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	merged := []timeWindow{}
+	for _, window := range windows {
+		if last := len(merged) - 1; last >= 0 && !window.Start.After(merged[last].End) {
+			if window.End.After(merged[last].End) {
+				merged[last].End = window.End
+			}
+			continue
+		}
+
+		merged = append(merged, window)
+	}
+
+	return merged
+}
+
+func inWindows(windows []timeWindow, t time.Time) bool {
+	// the last window which starts before the time
+	i := sort.Search(len(windows), func(i int) bool {
+		return windows[i].Start.After(t)
+	}) - 1
+
+	return i >= 0 && !t.After(windows[i].End)
+}
+
+// CorrelateLogs finds the groups of the target log which ran while the records of the source group were being served.
+// The result is sorted by the total value (or the count without ValueColumn).
+func CorrelateLogs(options CorrelateLogsOptions) ([]CorrelatedGroup, error) {
+	sourceRecords, ok := options.Source.Records[options.Key]
+	if !ok {
+		return nil, fmt.Errorf("Group not found: %v", options.Key)
+	}
+
+	valueIndex := -1
+	if options.ValueColumn != "" {
+		valueIndex = options.Target.Columns.GetIndex(options.ValueColumn)
+		if valueIndex < 0 {
+			return nil, fmt.Errorf("Unknown column: %v", options.ValueColumn)
+		}
+	}
+
+	var matches func(record LogRecordRow) bool
+	if options.JoinColumn != "" {
+		sourceIndex := options.Source.Columns.GetIndex(options.JoinColumn)
+		targetIndex := options.Target.Columns.GetIndex(options.JoinColumn)
+		if sourceIndex < 0 || targetIndex < 0 {
+			return nil, fmt.Errorf("Unknown column: %v", options.JoinColumn)
+		}
+
+		ids := map[string]bool{}
+		for _, record := range sourceRecords {
+			// "-" is what nginx logs for a missing value
+			if id := rawString(record[sourceIndex]); id != "" && id != "-" {
+				ids[id] = true
+			}
+		}
+
+		matches = func(record LogRecordRow) bool {
+			return ids[rawString(record[targetIndex])]
+		}
+	} else {
+		sourceIndex := options.Source.Columns.GetIndex("Timestamp")
+		targetIndex := options.Target.Columns.GetIndex("Timestamp")
+		if sourceIndex < 0 || targetIndex < 0 {
+			return nil, fmt.Errorf("Both logs need the Timestamp column")
+		}
+
+		durationIndex := -1
+		if options.DurationColumn != "" {
+			durationIndex = options.Source.Columns.GetIndex(options.DurationColumn)
+			if durationIndex < 0 {
+				return nil, fmt.Errorf("Unknown column: %v", options.DurationColumn)
+			}
+			if !options.Source.Columns[durationIndex].Type.IsNumeric() {
+				return nil, fmt.Errorf("Column is not numeric: %v", options.DurationColumn)
+			}
+		}
+
+		unit := options.DurationUnit
+		if unit == 0 {
+			unit = time.Second
+		}
+
+		windows := []timeWindow{}
+		for _, record := range sourceRecords {
+			end, ok := TimestampValue(record[sourceIndex])
+			if !ok {
+				continue
+			}
+
+			start := end
+			if durationIndex >= 0 {
+				if duration, ok := NumericValue(record[durationIndex]); ok {
+					start = end.Add(-time.Duration(duration * float64(unit)))
+				}
+			}
+
+			windows = append(windows, timeWindow{
+				Start: start.Add(-options.Margin),
+				End:   end.Add(options.Margin),
+			})
+		}
+		windows = mergeWindows(windows)
+
+		matches = func(record LogRecordRow) bool {
+			t, ok := TimestampValue(record[targetIndex])
+			return ok && inWindows(windows, t)
+		}
+	}
+
+	result := []CorrelatedGroup{}
+	for key, records := range options.Target.Records {
+		group := CorrelatedGroup{Key: key, Group: options.Target.Groups[key]}
+		for _, record := range records {
+			if !matches(record) {
+				continue
+			}
+
+			group.Count++
+			if valueIndex >= 0 {
+				if value, ok := NumericValue(record[valueIndex]); ok {
+					group.Total += value
+				}
+			}
+		}
+
+		if group.Count > 0 {
+			result = append(result, group)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b CorrelatedGroup) int {
+		return cmp.Or(
+			cmp.Compare(b.Total, a.Total),
+			cmp.Compare(b.Count, a.Count),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+
+	return result, nil
+}
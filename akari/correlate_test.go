@@ -0,0 +1,91 @@
+package akari
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelateLogs(t *testing.T) {
+	source := LogRecords{
+		Columns: LogRecordColumns{{Name: "Timestamp", Type: LogRecordTypeString}, {Name: "Url"}, {Name: "Time", Type: LogRecordTypeFloat64}, {Name: "RequestId"}},
+		Records: map[string]LogRecordRows{
+			"slow": {
+				{"2025-01-03T13:29:10Z", "/slow", 5.0, "a"},
+				{"2025-01-03T13:29:30Z", "/slow", 2.0, "-"},
+			},
+		},
+	}
+	target := LogRecords{
+		Columns: LogRecordColumns{{Name: "Timestamp", Type: LogRecordTypeString}, {Name: "Query"}, {Name: "Elapsed", Type: LogRecordTypeFloat64}, {Name: "RequestId"}},
+		Records: map[string]LogRecordRows{
+			"select": {
+				{"2025-01-03T13:29:06Z", "select", 1.0, "a"},
+				{"2025-01-03T13:29:29Z", "select", 0.5, "b"},
+				{"2025-01-03T13:29:20Z", "select", 9.0, "c"},
+			},
+			"update": {
+				{"2025-01-03T13:29:04Z", "update", 0.1, "-"},
+			},
+			"insert": {
+				{"2025-01-03T13:29:00Z", "insert", 3.0, "-"},
+			},
+		},
+		Groups: map[string][]any{"select": {"select"}, "update": {"update"}, "insert": {"insert"}},
+	}
+
+	groups, err := CorrelateLogs(CorrelateLogsOptions{
+		Source:         source,
+		Key:            "slow",
+		DurationColumn: "Time",
+		Target:         target,
+		ValueColumn:    "Elapsed",
+		Margin:         time.Second,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []CorrelatedGroup{
+		{Key: "select", Group: []any{"select"}, Count: 2, Total: 1.5},
+		{Key: "update", Group: []any{"update"}, Count: 1, Total: 0.1},
+	}, groups)
+
+	groups, err = CorrelateLogs(CorrelateLogsOptions{
+		Source:     source,
+		Key:        "slow",
+		Target:     target,
+		JoinColumn: "RequestId",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []CorrelatedGroup{{Key: "select", Group: []any{"select"}, Count: 1}}, groups)
+
+	// the durations logged in milliseconds give the same windows
+	millis := LogRecords{
+		Columns: source.Columns,
+		Records: map[string]LogRecordRows{
+			"slow": {
+				{"2025-01-03T13:29:10Z", "/slow", 5000.0, "a"},
+				{"2025-01-03T13:29:30Z", "/slow", 2000.0, "-"},
+			},
+		},
+	}
+	groups, err = CorrelateLogs(CorrelateLogsOptions{
+		Source:         millis,
+		Key:            "slow",
+		DurationColumn: "Time",
+		DurationUnit:   time.Millisecond,
+		Target:         target,
+		ValueColumn:    "Elapsed",
+		Margin:         time.Second,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []CorrelatedGroup{
+		{Key: "select", Group: []any{"select"}, Count: 2, Total: 1.5},
+		{Key: "update", Group: []any{"update"}, Count: 1, Total: 0.1},
+	}, groups)
+
+	_, err = CorrelateLogs(CorrelateLogsOptions{Source: source, Key: "slow", DurationColumn: "Url", Target: target})
+	assert.Error(t, err)
+
+	_, err = CorrelateLogs(CorrelateLogsOptions{Source: source, Key: "unknown", Target: target})
+	assert.Error(t, err)
+}
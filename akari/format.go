@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strings"
+	"time"
 )
 
 type SummaryRecordColumn struct {
@@ -15,11 +17,22 @@ type SummaryRecordColumn struct {
 type SummaryRowCell struct {
 	Value     any
 	PrevValue any
+	// p-value of the significance test between the current and previous values (nil if not tested)
+	PValue *float64
 }
 
 type SummaryRecords struct {
 	Columns []SummaryRecordColumn
 	Rows    map[string][]SummaryRowCell
+	HasPrev bool
+	// Rows which exist only in the previous records. Only PrevValue is set.
+	Gone map[string][]SummaryRowCell
+	// Row for the groups beyond the limit (nil if there are none)
+	Other        []SummaryRowCell
+	GroupingKeys []string
+	Groups       map[string][]any
+	// nil unless the outliers are enabled
+	Outliers *Outliers
 }
 
 func (r SummaryRecords) GetIndex(key string) int {
@@ -32,71 +45,185 @@ func (r SummaryRecords) GetIndex(key string) int {
 	return -1
 }
 
+type GroupStatus string
+
+const (
+	GroupStatusNew  GroupStatus = "new"
+	GroupStatusGone GroupStatus = "gone"
+)
+
 type SummaryRecordKeyPair struct {
 	Key    string
+	Group  []any
+	Status GroupStatus
 	Record []SummaryRowCell
 }
 
 type SummaryRecordKeyPairs struct {
-	Columns []SummaryRecordColumn
-	Entries []SummaryRecordKeyPair
+	Columns      []SummaryRecordColumn
+	GroupingKeys []string
+	Entries      []SummaryRecordKeyPair
 }
 
 func (r SummaryRecords) GetKeyPairs() SummaryRecordKeyPairs {
 	entries := []SummaryRecordKeyPair{}
 	for key, record := range r.Rows {
+		var status GroupStatus
+		if r.HasPrev && len(record) > 0 && record[0].PrevValue == nil {
+			status = GroupStatusNew
+		}
+
 		entries = append(entries, SummaryRecordKeyPair{
 			Key:    key,
+			Group:  r.Groups[key],
+			Status: status,
 			Record: record,
 		})
 	}
 
 	return SummaryRecordKeyPairs{
-		Columns: r.Columns,
-		Entries: entries,
+		Columns:      r.Columns,
+		GroupingKeys: r.GroupingKeys,
+		Entries:      entries,
 	}
 }
 
+func (r SummaryRecords) GetGoneKeyPairs() SummaryRecordKeyPairs {
+	entries := []SummaryRecordKeyPair{}
+	for key, record := range r.Gone {
+		entries = append(entries, SummaryRecordKeyPair{
+			Key:    key,
+			Group:  r.Groups[key],
+			Status: GroupStatusGone,
+			Record: record,
+		})
+	}
+
+	return SummaryRecordKeyPairs{
+		Columns:      r.Columns,
+		GroupingKeys: r.GroupingKeys,
+		Entries:      entries,
+	}
+}
+
+type SortKey struct {
+	Name       string
+	Index      int
+	Descending bool
+	Diff       bool
+}
+
+// ParseSortKey parses keys like "Total", "-Total", "+Url" or "-diff:P95".
+// Keys without a direction are sorted in descending order.
+func ParseSortKey(key string) SortKey {
+	sortKey := SortKey{
+		Name:       key,
+		Index:      -1,
+		Descending: true,
+	}
+
+	if name, ok := strings.CutPrefix(sortKey.Name, "+"); ok {
+		sortKey.Name = name
+		sortKey.Descending = false
+	} else if name, ok := strings.CutPrefix(sortKey.Name, "-"); ok {
+		sortKey.Name = name
+	}
+
+	if name, ok := strings.CutPrefix(sortKey.Name, "diff:"); ok {
+		sortKey.Name = name
+		sortKey.Diff = true
+	}
+
+	return sortKey
+}
+
 type SortByOptions struct {
-	SortKeyIndexes []int
-	UsePrev        bool
+	SortKeys []SortKey
+	UsePrev  bool
+}
+
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return cmp.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		slog.Error("Unsupported type for sorting")
+		return 0
+	}
 }
 
 func (r *SummaryRecordKeyPairs) SortBy(options SortByOptions) {
 	records := *r
 
-	slices.SortStableFunc(records.Entries, func(a, b SummaryRecordKeyPair) int {
-		sortingKeys := []int{}
-		for _, sortKey := range options.SortKeyIndexes {
-			valueA := a.Record[sortKey].Value
-			if options.UsePrev {
-				valueA = a.Record[sortKey].PrevValue
+	sortValue := func(cell SummaryRowCell, sortKey SortKey) any {
+		if options.UsePrev {
+			return cell.PrevValue
+		}
+		if sortKey.Diff {
+			if cell.PrevValue == nil {
+				return nil
 			}
 
-			valueB := b.Record[sortKey].Value
-			if options.UsePrev {
-				valueB = b.Record[sortKey].PrevValue
-			}
+			return RelativeDiff(cell.Value, cell.PrevValue)
+		}
+
+		return cell.Value
+	}
 
-			if valueA == nil {
+	slices.SortStableFunc(records.Entries, func(a, b SummaryRecordKeyPair) int {
+		for _, sortKey := range options.SortKeys {
+			valueA := sortValue(a.Record[sortKey.Index], sortKey)
+			valueB := sortValue(b.Record[sortKey.Index], sortKey)
+
+			// nil values always come last
+			if valueA == nil && valueB == nil {
+				continue
+			} else if valueA == nil {
 				return 1
 			} else if valueB == nil {
 				return -1
 			}
 
-			switch valueA.(type) {
-			case int:
-				sortingKeys = append(sortingKeys, cmp.Compare(valueB.(int), valueA.(int)))
-			case float64:
-				sortingKeys = append(sortingKeys, cmp.Compare(valueB.(float64), valueA.(float64)))
-			case string:
-				sortingKeys = append(sortingKeys, cmp.Compare(valueB.(string), valueA.(string)))
-			default:
-				slog.Error("Unsupported type for sorting")
+			result := compareValues(valueA, valueB)
+			if sortKey.Descending {
+				result = -result
+			}
+			if result != 0 {
+				return result
 			}
 		}
 
-		return cmp.Or(sortingKeys...)
+		// keep the order deterministic
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	*r = records
+}
+
+func (r SummaryRecordKeyPairs) Keys() []string {
+	keys := []string{}
+	for _, entry := range r.Entries {
+		keys = append(keys, entry.Key)
+	}
+
+	return keys
+}
+
+// Fold keeps the first limit entries and replaces the rest with the given row
+func (r *SummaryRecordKeyPairs) Fold(limit int, other []SummaryRowCell) {
+	records := *r
+
+	records.Entries = append(records.Entries[:limit:limit], SummaryRecordKeyPair{
+		Key:    OtherGroupKey,
+		Record: other,
 	})
 
 	*r = records
@@ -111,33 +238,47 @@ type FormatColumnOptions struct {
 
 type FormatOptions struct {
 	ColumnOptions []FormatColumnOptions
-	Limit         int
 	AddRank       bool
 	PrevRanks     map[string]int
+	// Differences with p-value at or above Alpha are marked as insignificant
+	Alpha float64
 }
 
 func (r SummaryRecordKeyPairs) Format(options FormatOptions) TableData {
 	rows := []TableRow{}
 	for j, record := range r.Entries {
-		if options.Limit > 0 && j > options.Limit {
-			break
-		}
+		gone := record.Status == GroupStatusGone
 
 		row := []TableCell{}
 		if options.AddRank {
 			prev := 0
-			if len(options.PrevRanks) > 0 {
+			if len(options.PrevRanks) > 0 && record.Key != OtherGroupKey && record.Status == "" {
 				prev = options.PrevRanks[record.Key] + 1
 			}
 
+			value := fmt.Sprintf("%d", j+1)
+			rawValue := j + 1
+			if record.Key == OtherGroupKey {
+				value = ""
+			} else if gone {
+				value = ""
+				rawValue = 0
+			}
+
 			row = append(row, TableCell{
-				Value:        fmt.Sprintf("%d", j+1),
-				RawValue:     j + 1,
+				Value:        value,
+				RawValue:     rawValue,
 				PrevRawValue: prev,
 				Alignment:    TableColumnAlignmentRight,
 			})
 		}
 		for i, cell := range record.Record {
+			// gone rows show the previous values
+			value := cell.Value
+			if gone {
+				value = cell.PrevValue
+			}
+
 			format := options.ColumnOptions[i].Format
 			if format == "" {
 				if r.Columns[i].Type.IsFloat() {
@@ -145,9 +286,13 @@ func (r SummaryRecordKeyPairs) Format(options FormatOptions) TableData {
 				} else {
 					format = "%v"
 				}
+
+				if t, ok := value.(time.Time); ok {
+					value = t.Format(time.DateTime)
+				}
 			}
 			if options.ColumnOptions[i].HumanizeBytes {
-				cell.Value = HumanizeBytes(cell.Value.(int))
+				value = HumanizeBytes(value.(int))
 			}
 
 			alignment := options.ColumnOptions[i].Alignment
@@ -160,16 +305,20 @@ func (r SummaryRecordKeyPairs) Format(options FormatOptions) TableData {
 			}
 
 			row = append(row, TableCell{
-				Value:        fmt.Sprintf(format, cell.Value),
-				RawValue:     cell.Value,
-				PrevRawValue: cell.PrevValue,
-				Alignment:    alignment,
+				Value:         fmt.Sprintf(format, value),
+				RawValue:      cell.Value,
+				PrevRawValue:  cell.PrevValue,
+				PValue:        cell.PValue,
+				Insignificant: cell.PValue != nil && *cell.PValue >= options.Alpha,
+				Alignment:     alignment,
 			})
 		}
 
 		rows = append(rows, TableRow{
-			Key:   record.Key,
-			Cells: row,
+			Key:    record.Key,
+			Group:  record.Group,
+			Status: record.Status,
+			Cells:  row,
 		})
 	}
 
@@ -177,6 +326,7 @@ func (r SummaryRecordKeyPairs) Format(options FormatOptions) TableData {
 	if options.AddRank {
 		columns = append(columns, TableColumn{
 			Name:      "#",
+			Type:      LogRecordTypeInt,
 			Alignment: TableColumnAlignmentRight,
 		})
 	}
@@ -192,12 +342,14 @@ func (r SummaryRecordKeyPairs) Format(options FormatOptions) TableData {
 
 		columns = append(columns, TableColumn{
 			Name:      column.Name,
+			Type:      r.Columns[k].Type,
 			Alignment: alignment,
 		})
 	}
 
 	return TableData{
-		Columns: columns,
-		Rows:    rows,
+		Columns:      columns,
+		GroupingKeys: r.GroupingKeys,
+		Rows:         rows,
 	}
 }
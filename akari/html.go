@@ -19,8 +19,9 @@ type HtmlTableCell struct {
 }
 
 type HtmlTableRow struct {
-	Key   string
-	Cells []HtmlTableCell
+	Key    string
+	Status GroupStatus
+	Cells  []HtmlTableCell
 }
 
 type HtmlTableData struct {
@@ -40,7 +41,7 @@ func HtmlStyle(style map[string]string) template.CSS {
 func HtmlAttrs(attrs map[string]string) template.HTMLAttr {
 	result := []string{}
 	for key, value := range attrs {
-		result = append(result, fmt.Sprintf(`%v="%v"`, key, value))
+		result = append(result, fmt.Sprintf(`%v="%v"`, key, template.HTMLEscapeString(value)))
 	}
 
 	return template.HTMLAttr(strings.Join(result, " "))
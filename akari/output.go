@@ -0,0 +1,182 @@
+package akari
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+type OutputFormat string
+
+const (
+	OutputFormatText     OutputFormat = "text"
+	OutputFormatJSON     OutputFormat = "json"
+	OutputFormatCSV      OutputFormat = "csv"
+	OutputFormatTSV      OutputFormat = "tsv"
+	OutputFormatMarkdown OutputFormat = "markdown"
+)
+
+var OutputFormats = []OutputFormat{
+	OutputFormatText,
+	OutputFormatJSON,
+	OutputFormatCSV,
+	OutputFormatTSV,
+	OutputFormatMarkdown,
+}
+
+func (d TableData) WriteAs(w io.Writer, format OutputFormat) error {
+	switch format {
+	case OutputFormatText, "":
+		d.Write(w)
+		d.WriteOutliers(w)
+		return nil
+	case OutputFormatJSON:
+		return d.WriteJSON(w)
+	case OutputFormatCSV:
+		return d.WriteCSV(w, ',')
+	case OutputFormatTSV:
+		return d.WriteCSV(w, '\t')
+	case OutputFormatMarkdown:
+		return d.WriteMarkdown(w)
+	default:
+		return fmt.Errorf("Unknown output format: %v", format)
+	}
+}
+
+// jsonValue makes raw values encodable; NaN and Inf are not valid JSON numbers
+func jsonValue(value any) any {
+	if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return nil
+	}
+
+	return value
+}
+
+func (c TableCell) MarshalJSON() ([]byte, error) {
+	type tableCell TableCell
+
+	cell := tableCell(c)
+	cell.RawValue = jsonValue(c.RawValue)
+	cell.PrevRawValue = jsonValue(c.PrevRawValue)
+
+	return json.Marshal(cell)
+}
+
+func (o Outlier) MarshalJSON() ([]byte, error) {
+	type outlier Outlier
+
+	return json.Marshal(struct {
+		outlier
+		Value any
+	}{outlier(o), jsonValue(o.Value)})
+}
+
+func (d TableData) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(d); err != nil {
+		return fmt.Errorf("Failed to encode json (%w)", err)
+	}
+
+	return nil
+}
+
+// WriteOutliers lists the outliers of each group in the order of the rows
+func (d TableData) WriteOutliers(w io.Writer) {
+	if d.Outliers == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "\nOutliers by %v\n", d.Outliers.Column)
+	for _, row := range d.Rows {
+		outliers := d.Outliers.Rows[row.Key]
+		if len(outliers) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%v\n", row.Label())
+		for _, outlier := range outliers {
+			fmt.Fprintf(w, "  %10.3f  %v  L%d  %v\n", outlier.Value, outlier.Timestamp, outlier.Line.Number, outlier.Line.Text)
+		}
+	}
+}
+
+// rawString formats raw values for machine-readable outputs
+func rawString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// WriteCSV writes raw values instead of the formatted ones so that the output can be processed by scripts
+func (d TableData) WriteCSV(w io.Writer, comma rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+
+	headers := []string{}
+	for _, column := range d.Columns {
+		headers = append(headers, column.Name)
+	}
+	if err := writer.Write(headers); err != nil {
+		return fmt.Errorf("Failed to write header (%w)", err)
+	}
+
+	for _, row := range d.Rows {
+		values := []string{}
+		for _, cell := range row.Cells {
+			values = append(values, rawString(cell.RawValue))
+		}
+
+		if err := writer.Write(values); err != nil {
+			return fmt.Errorf("Failed to write row (%w)", err)
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func markdownEscape(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+func (d TableData) WriteMarkdown(w io.Writer) error {
+	headers := []string{}
+	separators := []string{}
+	for _, column := range d.Columns {
+		headers = append(headers, markdownEscape(column.Name))
+		if column.Alignment == TableColumnAlignmentRight {
+			separators = append(separators, "---:")
+		} else {
+			separators = append(separators, "---")
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "| %s |\n| %s |\n", strings.Join(headers, " | "), strings.Join(separators, " | ")); err != nil {
+		return fmt.Errorf("Failed to write header (%w)", err)
+	}
+
+	for _, row := range d.Rows {
+		values := []string{}
+		for _, cell := range row.Cells {
+			values = append(values, markdownEscape(strings.TrimSpace(cell.Value)))
+		}
+
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(values, " | ")); err != nil {
+			return fmt.Errorf("Failed to write row (%w)", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package akari
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTableData() TableData {
+	return TableData{
+		Columns: []TableColumn{
+			{Name: "Count", Type: LogRecordTypeInt, Alignment: TableColumnAlignmentRight},
+			{Name: "Mean", Type: LogRecordTypeFloat64, Alignment: TableColumnAlignmentRight},
+			{Name: "Url", Type: LogRecordTypeString, Alignment: TableColumnAlignmentLeft},
+		},
+		Rows: []TableRow{
+			{Key: `["/a|b"]`, Cells: []TableCell{
+				{Value: "2", RawValue: 2},
+				{Value: "0.125", RawValue: 0.125, PrevRawValue: 0.25},
+				{Value: "/a|b", RawValue: "/a|b"},
+			}},
+			{Key: `["/c"]`, Cells: []TableCell{
+				{Value: "0", RawValue: 0},
+				{Value: "NaN", RawValue: math.NaN()},
+				{Value: "/c", RawValue: "/c"},
+			}},
+		},
+	}
+}
+
+func TestWriteAs(t *testing.T) {
+	data := testTableData()
+
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, data.WriteAs(buf, OutputFormatMarkdown))
+	assert.Equal(t, `| Count | Mean | Url |
+| ---: | ---: | --- |
+| 2 | 0.125 | /a\|b |
+| 0 | NaN | /c |
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, data.WriteAs(buf, OutputFormatTSV))
+	assert.Equal(t, "Count\tMean\tUrl\n2\t0.125\t/a|b\n0\tNaN\t/c\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, data.WriteAs(buf, OutputFormatJSON))
+
+	var decoded struct {
+		Columns []TableColumn
+		Rows    []struct {
+			Cells []struct {
+				RawValue     any
+				PrevRawValue any
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, data.Columns, decoded.Columns)
+	assert.Equal(t, 0.125, decoded.Rows[0].Cells[1].RawValue)
+	assert.Equal(t, 0.25, decoded.Rows[0].Cells[1].PrevRawValue)
+	assert.Nil(t, decoded.Rows[1].Cells[1].RawValue)
+
+	assert.Error(t, data.WriteAs(buf, OutputFormat("xml")))
+}
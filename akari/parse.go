@@ -2,12 +2,13 @@ package akari
 
 import (
 	"bufio"
-	"encoding/base64"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"regexp"
-
-	"github.com/pierrec/xxHash/xxHash64"
+	"slices"
+	"strings"
 )
 
 type ParseColumnOptions struct {
@@ -18,17 +19,35 @@ type ParseColumnOptions struct {
 }
 
 type ParseOptions struct {
-	RegExp   *regexp.Regexp
-	Columns  []ParseColumnOptions
-	Keys     []string
-	HashSeed uint64
+	RegExp  *regexp.Regexp
+	Columns []ParseColumnOptions
+	Keys    []string
+	// keeps the original lines of the records in LogRecords.Lines
+	KeepLines bool
+	// the number of lines before the reader, when parsing the rest of a log
+	LineOffset int
+}
+
+// GroupKey encodes grouping values as a JSON array.
+// Unlike a hash, the key is readable, collision-free and stable across runs.
+func GroupKey(values []any) (string, error) {
+	// the keys are shown in URLs, so "&" in query strings is kept as is
+	var key bytes.Buffer
+	encoder := json.NewEncoder(&key)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(values); err != nil {
+		return "", fmt.Errorf("Failed to encode group key %v (%w)", values, err)
+	}
+
+	return strings.TrimSuffix(key.String(), "\n"), nil
 }
 
 func Parse(options ParseOptions, r io.Reader, logger DebugLogger) (LogRecords, error) {
 	scanner := bufio.NewScanner(r)
 
-	hash := xxHash64.New(options.HashSeed)
 	records := map[string]LogRecordRows{}
+	groups := map[string][]any{}
+	lines := map[string][]LogLine{}
 
 	logger.Debug("Start scanning")
 
@@ -45,18 +64,27 @@ func Parse(options ParseOptions, r io.Reader, logger DebugLogger) (LogRecords, e
 	resultTypes := map[string]LogRecordType{}
 
 	tokensLines := [][]string{}
+	lineNumbers := []int{}
+	// the scanned lines, since the match of an unanchored regexp is only a part of the line
+	texts := []string{}
+	lineNumber := options.LineOffset
 	for scanner.Scan() {
+		lineNumber++
 		line := scanner.Text()
 		if len(line) == 0 {
 			continue
 		}
 
 		tokensLines = append(tokensLines, options.RegExp.FindStringSubmatch(line))
+		lineNumbers = append(lineNumbers, lineNumber)
+		if options.KeepLines {
+			texts = append(texts, line)
+		}
 	}
 
 	logger.Debug("Scan finished", "lines", len(tokensLines))
 
-	for _, tokens := range tokensLines {
+	for i, tokens := range tokensLines {
 		row := []any{}
 		key := []any{}
 
@@ -89,22 +117,42 @@ func Parse(options ParseOptions, r io.Reader, logger DebugLogger) (LogRecords, e
 			row = append(row, valueAny)
 		}
 
-		hashKey := base64.RawStdEncoding.EncodeToString(hash.Sum([]byte(fmt.Sprintf("%v", key))))
-		records[string(hashKey)] = append(records[string(hashKey)], row)
+		groupKey, err := GroupKey(key)
+		if err != nil {
+			return LogRecords{}, err
+		}
+
+		records[groupKey] = append(records[groupKey], row)
+		groups[groupKey] = key
+		if options.KeepLines {
+			lines[groupKey] = append(lines[groupKey], LogLine{Number: lineNumbers[i], Text: texts[i]})
+		}
 	}
 
 	logger.Debug("Processing tokens finished")
 
 	columns := []LogRecordColumn{}
+	groupingKeys := []string{}
 	for _, column := range options.Columns {
 		columns = append(columns, LogRecordColumn{
 			Name: column.Name,
 			Type: resultTypes[column.Name],
 		})
+
+		if slices.Contains(options.Keys, column.Name) {
+			groupingKeys = append(groupingKeys, column.Name)
+		}
+	}
+
+	result := LogRecords{
+		Columns:      columns,
+		Records:      records,
+		GroupingKeys: groupingKeys,
+		Groups:       groups,
+	}
+	if options.KeepLines {
+		result.Lines = lines
 	}
 
-	return LogRecords{
-		Columns: columns,
-		Records: records,
-	}, nil
+	return result, nil
 }
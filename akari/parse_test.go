@@ -0,0 +1,86 @@
+package akari
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupKey(t *testing.T) {
+	joined, err := GroupKey([]any{"a b"})
+	assert.NoError(t, err)
+	splitted, err := GroupKey([]any{"a", "b"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, `["a b"]`, joined)
+	assert.Equal(t, `["a","b"]`, splitted)
+
+	key, err := GroupKey([]any{"GET", "/api/users", 200})
+	assert.NoError(t, err)
+	assert.Equal(t, `["GET","/api/users",200]`, key)
+
+	key, err = GroupKey([]any{"GET", "/api/users?page=1&limit=<10>"})
+	assert.NoError(t, err)
+	assert.Equal(t, `["GET","/api/users?page=1&limit=<10>"]`, key)
+}
+
+func TestParseKeepLines(t *testing.T) {
+	options, err := testAnalyzerConfig().ParseOptions()
+	assert.NoError(t, err)
+	options.KeepLines = true
+
+	parsed, err := Parse(options, strings.NewReader("GET /a 1.0\n\nGET /b 3.0\nGET /a 2.0\n"), slog.Default())
+	assert.NoError(t, err)
+
+	key, err := GroupKey([]any{"GET", "/a"})
+	assert.NoError(t, err)
+
+	entries := parsed.Entries(key)
+	SortLogEntries(entries, parsed.Columns.GetIndex("Time"), true)
+	assert.Equal(t, []LogEntry{
+		{Line: LogLine{Number: 4, Text: "GET /a 2.0"}, Record: LogRecordRow{"GET", "/a", 2.0}},
+		{Line: LogLine{Number: 1, Text: "GET /a 1.0"}, Record: LogRecordRow{"GET", "/a", 1.0}},
+	}, entries)
+
+	// an unanchored regexp matches only a part of the line, but the whole line is kept
+	options.RegExp = regexp.MustCompile(`(?P<Method>GET|POST) (?P<Url>\S+) (?P<Time>\S+)`)
+	parsed, err = Parse(options, strings.NewReader("127.0.0.1 GET /a 1.0 \"curl\"\n"), slog.Default())
+	assert.NoError(t, err)
+
+	assert.Equal(t, []LogLine{{Number: 1, Text: `127.0.0.1 GET /a 1.0 "curl"`}}, parsed.Lines[key])
+}
+
+func TestLogRecordsAppend(t *testing.T) {
+	options, err := testAnalyzerConfig().ParseOptions()
+	assert.NoError(t, err)
+	options.KeepLines = true
+
+	whole, err := Parse(options, strings.NewReader("GET /a 1.0\nGET /b 2.0\n\nGET /a 3.0\n"), slog.Default())
+	assert.NoError(t, err)
+
+	parsed, err := Parse(options, strings.NewReader("GET /a 1.0\nGET /b 2.0\n"), slog.Default())
+	assert.NoError(t, err)
+
+	// the rest of the log, after the 2 lines parsed above
+	options.LineOffset = 2
+	rest, err := Parse(options, strings.NewReader("\nGET /a 3.0\n"), slog.Default())
+	assert.NoError(t, err)
+
+	parsed.Append(rest)
+	assert.Equal(t, whole, parsed)
+
+	// nothing appended keeps the column types
+	empty, err := Parse(options, strings.NewReader(""), slog.Default())
+	assert.NoError(t, err)
+	parsed.Append(empty)
+	assert.Equal(t, whole, parsed)
+
+	// the columns are known even before any record
+	var records LogRecords
+	records.Append(empty)
+	assert.Equal(t, empty.Columns, records.Columns)
+	assert.Equal(t, empty.GroupingKeys, records.GroupingKeys)
+}
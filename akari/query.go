@@ -3,6 +3,7 @@ package akari
 import (
 	"fmt"
 	"slices"
+	"time"
 )
 
 type QueryFunction string
@@ -50,6 +51,17 @@ func (f QueryFunction) ResultType(originalType LogRecordType) (LogRecordType, er
 	}
 }
 
+// IsStatistic reports whether the function describes the distribution of the values,
+// which can be compared by a significance test
+func (f QueryFunction) IsStatistic() bool {
+	switch f {
+	case QueryFunctionCount, QueryFunctionSum, QueryFunctionAny:
+		return false
+	default:
+		return true
+	}
+}
+
 func evaluate[T int | float64](f QueryFunction, values []T) (any, error) {
 	switch f {
 	case QueryFunctionCount:
@@ -129,6 +141,58 @@ type Query struct {
 	Filter   *QueryFilter
 }
 
+// Values returns the filtered numeric values of the column as float64
+func (a Query) Values(columns LogRecordColumns, records LogRecordRows) ([]float64, error) {
+	fromIndex := columns.GetIndex(a.From)
+	valueType := columns[fromIndex].Type
+
+	switch valueType {
+	case LogRecordTypeInt:
+		values, err := apply(a.Filter, GetLogRecordsNumbers[int](records, fromIndex))
+		if err != nil {
+			return nil, err
+		}
+
+		floats := []float64{}
+		for _, value := range values {
+			floats = append(floats, float64(value))
+		}
+
+		return floats, nil
+	case LogRecordTypeInt64:
+		fallthrough
+	case LogRecordTypeFloat64:
+		return apply(a.Filter, GetLogRecordsNumbers[float64](records, fromIndex))
+	default:
+		return nil, fmt.Errorf("Not a numeric column: %v", a.From)
+	}
+}
+
+// PValue compares the values of the records and the previous records.
+// It returns nil if the query is not a target of the test.
+func (a Query) PValue(test SignificanceTest, columns LogRecordColumns, records LogRecordRows, prevRecords LogRecordRows) (*float64, error) {
+	if test == SignificanceTestNone || !a.Function.IsStatistic() || !columns[columns.GetIndex(a.From)].Type.IsNumeric() {
+		return nil, nil
+	}
+
+	values, err := a.Values(columns, records)
+	if err != nil {
+		return nil, err
+	}
+
+	prevValues, err := a.Values(columns, prevRecords)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := test.PValue(values, prevValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
 func (a Query) Apply(columns LogRecordColumns, records LogRecordRows) (any, LogRecordType, error) {
 	fromIndex := columns.GetIndex(a.From)
 	valueType := columns[columns.GetIndex(a.From)].Type
@@ -190,6 +254,26 @@ func (a Query) Apply(columns LogRecordColumns, records LogRecordRows) (any, LogR
 		default:
 			return nil, "", fmt.Errorf("Unknown function: %v", a.Function)
 		}
+	case LogRecordTypeDateTime:
+		values := records.GetTimes(fromIndex)
+
+		t, err := a.Function.ResultType(valueType)
+		if err != nil {
+			return nil, "", err
+		}
+
+		switch a.Function {
+		case QueryFunctionCount:
+			return len(values), t, nil
+		case QueryFunctionAny:
+			return values[0], t, nil
+		case QueryFunctionMin:
+			return slices.MinFunc(values, time.Time.Compare), t, nil
+		case QueryFunctionMax:
+			return slices.MaxFunc(values, time.Time.Compare), t, nil
+		default:
+			return nil, "", fmt.Errorf("Unknown function: %v", a.Function)
+		}
 	default:
 		return nil, "", fmt.Errorf("Unknown value type: %v", valueType)
 	}
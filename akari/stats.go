@@ -0,0 +1,118 @@
+package akari
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+type SignificanceTest string
+
+const (
+	SignificanceTestNone         SignificanceTest = ""
+	SignificanceTestMannWhitneyU SignificanceTest = "mannWhitneyU"
+)
+
+func (t SignificanceTest) PValue(values []float64, prevValues []float64) (float64, error) {
+	switch t {
+	case SignificanceTestMannWhitneyU:
+		return MannWhitneyU(values, prevValues), nil
+	default:
+		return 0, fmt.Errorf("Unknown significance test: %v", t)
+	}
+}
+
+// MannWhitneyU returns the two-sided p-value of the Mann-Whitney U test using the normal approximation with tie correction
+func MannWhitneyU(xs []float64, ys []float64) float64 {
+	n1, n2 := len(xs), len(ys)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		value float64
+		first bool
+	}
+
+	samples := []sample{}
+	for _, x := range xs {
+		samples = append(samples, sample{value: x, first: true})
+	}
+	for _, y := range ys {
+		samples = append(samples, sample{value: y})
+	}
+
+	slices.SortFunc(samples, func(a, b sample) int {
+		if a.value < b.value {
+			return -1
+		} else if a.value > b.value {
+			return 1
+		}
+		return 0
+	})
+
+	n := float64(n1 + n2)
+	rankSum := 0.0
+	tieTerm := 0.0
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].value == samples[i].value {
+			j++
+		}
+
+		// average rank of the tied samples (ranks are 1-origin)
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if samples[k].first {
+				rankSum += rank
+			}
+		}
+
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+
+	u := rankSum - float64(n1*(n1+1))/2
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+
+	// continuity correction
+	diff := math.Max(math.Abs(u-mean)-0.5, 0)
+	z := diff / math.Sqrt(variance)
+
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// PearsonCorrelation returns the correlation coefficient of the pairs. It is not defined if either of them has no variance.
+func PearsonCorrelation(xs []float64, ys []float64) (float64, bool) {
+	n := len(xs)
+	if n < 2 || n != len(ys) {
+		return 0, false
+	}
+
+	meanX, meanY := 0.0, 0.0
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+
+	if varianceX == 0 || varianceY == 0 {
+		return 0, false
+	}
+
+	return covariance / math.Sqrt(varianceX*varianceY), true
+}
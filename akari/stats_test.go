@@ -0,0 +1,58 @@
+package akari
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	assert.InDelta(t, 0.012186, MannWhitneyU([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}), 1e-6)
+	assert.InDelta(t, 1.0, MannWhitneyU([]float64{1, 2, 3}, []float64{1, 2, 3}), 1e-9)
+	assert.Equal(t, 1.0, MannWhitneyU([]float64{1, 1}, []float64{1, 1}))
+	assert.Equal(t, 1.0, MannWhitneyU(nil, []float64{1}))
+}
+
+func TestPearsonCorrelation(t *testing.T) {
+	r, ok := PearsonCorrelation([]float64{1, 2, 3}, []float64{2, 4, 6})
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0, r, 1e-9)
+
+	r, ok = PearsonCorrelation([]float64{1, 2, 3}, []float64{3, 1, 2})
+	assert.True(t, ok)
+	assert.InDelta(t, -0.5, r, 1e-9)
+
+	_, ok = PearsonCorrelation([]float64{1, 1, 1}, []float64{1, 2, 3})
+	assert.False(t, ok)
+}
+
+func TestAnalyzeSignificance(t *testing.T) {
+	config := testAnalyzerConfig()
+	config.Query[0].Columns = append(config.Query[0].Columns, QueryConfig{Name: ptr("Mean"), Function: QueryFunctionMean})
+	config.Diffs = []string{"Mean"}
+	config.Significance = &SignificanceConfig{Alpha: 0.05}
+
+	result := testAnalyze(t, config, `GET /a 1.0
+GET /a 1.2
+GET /b 5.0
+GET /b 5.1
+GET /b 5.2
+GET /b 5.3
+GET /b 5.4
+GET /b 5.5
+`, `GET /a 1.1
+GET /a 1.3
+GET /b 1.0
+GET /b 1.1
+GET /b 1.2
+GET /b 1.3
+GET /b 1.4
+GET /b 1.5
+`)
+
+	assert.Equal(t, "/b", result.Rows[0].Cells[4].Value)
+	assert.False(t, result.Rows[0].Cells[2].Insignificant)
+	assert.Equal(t, "/a", result.Rows[1].Cells[4].Value)
+	assert.True(t, result.Rows[1].Cells[2].Insignificant)
+	assert.Nil(t, result.Rows[1].Cells[0].PValue)
+}
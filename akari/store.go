@@ -0,0 +1,243 @@
+package akari
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func init() {
+	// types which can be stored in SummaryRowCell and the group values
+	gob.Register(time.Time{})
+}
+
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open file (%w)", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("Failed to hash file (%w)", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Fingerprint identifies the config. Results summarized with a different config are not reused.
+func (config AnalyzerConfig) Fingerprint() (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode config (%w)", err)
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+type SnapshotKey struct {
+	LogPath           string
+	ContentHash       string
+	PrevContentHash   string
+	ConfigFingerprint string
+}
+
+// id does not include the content hash, so that a growing log replaces its previous snapshot
+func (k SnapshotKey) id() string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%v\x00%v", k.LogPath, k.PrevContentHash)))
+	return hex.EncodeToString(hash[:16])
+}
+
+type Snapshot struct {
+	SnapshotKey
+	Analyzer  string
+	CreatedAt time.Time
+	Summary   SummaryRecords
+}
+
+// SnapshotStore saves summaries on the local disk, one gob file for each snapshot
+type SnapshotStore struct {
+	Dir string
+}
+
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{Dir: dir}
+}
+
+func (s *SnapshotStore) path(key SnapshotKey) string {
+	return filepath.Join(s.Dir, key.ConfigFingerprint[:16], key.id()+".gob")
+}
+
+func (s *SnapshotStore) Load(key SnapshotKey) (Snapshot, bool, error) {
+	file, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, false, nil
+	} else if err != nil {
+		return Snapshot{}, false, fmt.Errorf("Failed to open snapshot (%w)", err)
+	}
+	defer file.Close()
+
+	var snapshot Snapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		return Snapshot{}, false, fmt.Errorf("Failed to decode snapshot (%w)", err)
+	}
+
+	return snapshot, snapshot.SnapshotKey == key, nil
+}
+
+func (s *SnapshotStore) Save(snapshot Snapshot) error {
+	path := s.path(snapshot.SnapshotKey)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("Failed to create store directory (%w)", err)
+	}
+
+	// write to a temporary file first so that readers never see a partial snapshot
+	file, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("Failed to create snapshot (%w)", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := gob.NewEncoder(file).Encode(snapshot); err != nil {
+		file.Close()
+		return fmt.Errorf("Failed to encode snapshot (%w)", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Failed to write snapshot (%w)", err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("Failed to save snapshot (%w)", err)
+	}
+
+	return nil
+}
+
+// List returns the snapshots summarized with the config
+func (s *SnapshotStore) List(configFingerprint string) ([]Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, configFingerprint[:16], "*.gob"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list snapshots (%w)", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open snapshot (%w)", err)
+		}
+
+		var snapshot Snapshot
+		err = gob.NewDecoder(file).Decode(&snapshot)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode snapshot %v (%w)", path, err)
+		}
+
+		if snapshot.ConfigFingerprint == configFingerprint {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, nil
+}
+
+type SummarizeFileOptions struct {
+	Config   AnalyzerConfig
+	LogPath  string
+	PrevPath string
+	Logger   DebugLogger
+}
+
+// SummarizeFile summarizes the log file (and the previous file if given), reusing the stored snapshot if it exists
+func (s *SnapshotStore) SummarizeFile(options SummarizeFileOptions) (SummaryRecords, error) {
+	fingerprint, err := options.Config.Fingerprint()
+	if err != nil {
+		return SummaryRecords{}, err
+	}
+
+	contentHash, err := HashFile(options.LogPath)
+	if err != nil {
+		return SummaryRecords{}, err
+	}
+
+	prevContentHash := ""
+	if options.PrevPath != "" {
+		prevContentHash, err = HashFile(options.PrevPath)
+		if err != nil {
+			return SummaryRecords{}, err
+		}
+	}
+
+	key := SnapshotKey{
+		LogPath:           options.LogPath,
+		ContentHash:       contentHash,
+		PrevContentHash:   prevContentHash,
+		ConfigFingerprint: fingerprint,
+	}
+
+	snapshot, ok, err := s.Load(key)
+	if err != nil {
+		options.Logger.Debug("Ignored broken snapshot", "error", err)
+	} else if ok {
+		options.Logger.Debug("Loaded snapshot", "path", options.LogPath)
+		return snapshot.Summary, nil
+	}
+
+	summary, err := SummarizeFile(options)
+	if err != nil {
+		return SummaryRecords{}, err
+	}
+
+	// the summary is still valid without the store, so a failed save is not an error
+	if err := s.Save(Snapshot{
+		SnapshotKey: key,
+		Analyzer:    options.Config.Name,
+		CreatedAt:   time.Now(),
+		Summary:     summary,
+	}); err != nil {
+		slog.Warn("Failed to save snapshot", "path", options.LogPath, "error", err)
+	} else {
+		options.Logger.Debug("Saved snapshot", "path", options.LogPath)
+	}
+
+	return summary, nil
+}
+
+// SummarizeFile summarizes the log file without the store
+func SummarizeFile(options SummarizeFileOptions) (SummaryRecords, error) {
+	logFile, err := os.Open(options.LogPath)
+	if err != nil {
+		return SummaryRecords{}, fmt.Errorf("Failed to open log file (%w)", err)
+	}
+	defer logFile.Close()
+
+	var prev io.Reader
+	if options.PrevPath != "" {
+		prevFile, err := os.Open(options.PrevPath)
+		if err != nil {
+			return SummaryRecords{}, fmt.Errorf("Failed to open previous log file (%w)", err)
+		}
+		defer prevFile.Close()
+
+		prev = prevFile
+	}
+
+	return Summarize(AnalyzeOptions{
+		Config:  options.Config,
+		Source:  logFile,
+		HasPrev: prev != nil,
+		Prev:    prev,
+		Logger:  options.Logger,
+	})
+}
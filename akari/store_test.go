@@ -0,0 +1,87 @@
+package akari
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotStoreSummarizeFile(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "access.log")
+	prevPath := filepath.Join(dir, "access.prev.log")
+	assert.NoError(t, os.WriteFile(logPath, []byte("GET /a 1.0\nGET /b 2.0\n"), 0o644))
+	assert.NoError(t, os.WriteFile(prevPath, []byte("GET /a 3.0\nGET /c 1.0\n"), 0o644))
+
+	config := testAnalyzerConfig()
+	store := NewSnapshotStore(filepath.Join(dir, "store"))
+	options := SummarizeFileOptions{
+		Config:   config,
+		LogPath:  logPath,
+		PrevPath: prevPath,
+		Logger:   slog.Default(),
+	}
+
+	expected, err := SummarizeFile(options)
+	assert.NoError(t, err)
+
+	summary, err := store.SummarizeFile(options)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, summary)
+
+	fingerprint, err := config.Fingerprint()
+	assert.NoError(t, err)
+
+	snapshots, err := store.List(fingerprint)
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, logPath, snapshots[0].LogPath)
+
+	// the stored snapshot is reused: a summary saved under the same key is returned as is
+	marked := snapshots[0]
+	marked.Summary.Rows = map[string][]SummaryRowCell{}
+	for key, row := range summary.Rows {
+		marked.Summary.Rows[key] = row
+		break
+	}
+	assert.NoError(t, store.Save(marked))
+
+	stored, err := store.SummarizeFile(options)
+	assert.NoError(t, err)
+	assert.Equal(t, marked.Summary, stored)
+	assert.NotEqual(t, expected, stored)
+
+	// a changed log file is summarized again
+	assert.NoError(t, os.WriteFile(logPath, []byte("GET /a 1.0\n"), 0o644))
+	summary, err = store.SummarizeFile(options)
+	assert.NoError(t, err)
+	assert.Len(t, summary.Rows, 1)
+
+	// a changed config does not see the snapshots of the others
+	config.Limit = 1
+	fingerprint, err = config.Fingerprint()
+	assert.NoError(t, err)
+
+	snapshots, err = store.List(fingerprint)
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestSnapshotStoreSummarizeFileWithoutStore(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "access.log")
+	assert.NoError(t, os.WriteFile(logPath, []byte("GET /a 1.0\nGET /b 2.0\n"), 0o644))
+
+	// the store directory cannot be created under a regular file
+	store := NewSnapshotStore(filepath.Join(logPath, "store"))
+	summary, err := store.SummarizeFile(SummarizeFileOptions{
+		Config:  testAnalyzerConfig(),
+		LogPath: logPath,
+		Logger:  slog.Default(),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, summary.Rows, 2)
+}
@@ -1,6 +1,13 @@
 package akari
 
-import "fmt"
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
 
 type LogRecordType string
 
@@ -41,8 +48,148 @@ type LogRecordRow []any
 type LogRecordRows []LogRecordRow
 
 type LogRecords struct {
-	Columns LogRecordColumns
-	Records map[string]LogRecordRows
+	Columns      LogRecordColumns
+	Records      map[string]LogRecordRows
+	GroupingKeys []string
+	Groups       map[string][]any
+	// the original lines of Records, only with ParseOptions.KeepLines
+	Lines map[string][]LogLine
+}
+
+// Append adds the records parsed from the rest of the same log
+func (r *LogRecords) Append(next LogRecords) {
+	// the column types are only known from the parsed records, but the columns are needed even without them
+	if len(next.Records) > 0 || r.Columns == nil {
+		r.Columns = next.Columns
+		r.GroupingKeys = next.GroupingKeys
+	}
+	if len(next.Records) == 0 {
+		return
+	}
+
+	if r.Records == nil {
+		r.Records = map[string]LogRecordRows{}
+		r.Groups = map[string][]any{}
+	}
+	for key, rows := range next.Records {
+		r.Records[key] = append(r.Records[key], rows...)
+		r.Groups[key] = next.Groups[key]
+	}
+
+	if next.Lines != nil {
+		if r.Lines == nil {
+			r.Lines = map[string][]LogLine{}
+		}
+		for key, lines := range next.Lines {
+			r.Lines[key] = append(r.Lines[key], lines...)
+		}
+	}
+}
+
+type LogLine struct {
+	Number int
+	Text   string
+}
+
+type LogEntry struct {
+	Line   LogLine
+	Record LogRecordRow
+}
+
+// Entries returns the records of the group with their lines, in the order of the file
+func (r LogRecords) Entries(key string) []LogEntry {
+	entries := []LogEntry{}
+	for i, record := range r.Records[key] {
+		entry := LogEntry{Record: record}
+		if i < len(r.Lines[key]) {
+			entry.Line = r.Lines[key][i]
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+type Outlier struct {
+	Line      LogLine
+	Timestamp string
+	Value     float64
+}
+
+type Outliers struct {
+	Column string
+	// the records with the largest values for each group key, from the largest
+	Rows map[string][]Outlier
+}
+
+// Outliers picks the records with the largest values of the column in each group. The records must be parsed with ParseOptions.KeepLines.
+func (r LogRecords) Outliers(column string, count int) (*Outliers, error) {
+	index := r.Columns.GetIndex(column)
+	if index < 0 || !r.Columns[index].Type.IsNumeric() {
+		return nil, fmt.Errorf("Not a numeric column: %v", column)
+	}
+	timestampIndex := r.Columns.GetIndex("Timestamp")
+
+	result := &Outliers{Column: column, Rows: map[string][]Outlier{}}
+	for key := range r.Records {
+		entries := r.Entries(key)
+		SortLogEntries(entries, index, true)
+
+		outliers := []Outlier{}
+		for _, entry := range entries {
+			if len(outliers) == count {
+				break
+			}
+
+			value, ok := NumericValue(entry.Record[index])
+			if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			outlier := Outlier{Line: entry.Line, Value: value}
+			if timestampIndex >= 0 {
+				outlier.Timestamp = rawString(entry.Record[timestampIndex])
+			}
+
+			outliers = append(outliers, outlier)
+		}
+
+		result.Rows[key] = outliers
+	}
+
+	return result, nil
+}
+
+// Fold picks the outliers of the groups folded into the other row, from the outliers of each group
+func (o *Outliers) Fold(keys []string, count int) {
+	outliers := []Outlier{}
+	for _, key := range keys {
+		outliers = append(outliers, o.Rows[key]...)
+	}
+
+	// the largest first, keeping the order of the file for the same values
+	slices.SortStableFunc(outliers, func(a, b Outlier) int {
+		if a.Value != b.Value {
+			return cmp.Compare(b.Value, a.Value)
+		}
+
+		return cmp.Compare(a.Line.Number, b.Line.Number)
+	})
+
+	o.Rows[OtherGroupKey] = outliers[:min(count, len(outliers))]
+}
+
+// SortLogEntries sorts the entries by the column, keeping the order of the file for the same values
+func SortLogEntries(entries []LogEntry, index int, descending bool) {
+	slices.SortStableFunc(entries, func(a, b LogEntry) int {
+		order := compareValues(a.Record[index], b.Record[index])
+		if descending {
+			return -order
+		}
+
+		return order
+	})
 }
 
 func GetLogRecordsNumbers[T int | float64](records LogRecordRows, index int) []T {
@@ -81,7 +228,18 @@ func (r LogRecordRows) GetStrings(index int) []string {
 	return strings
 }
 
-func (r LogRecords) Summarize(queries []Query, prevRows map[string]LogRecordRows) (SummaryRecords, error) {
+func (r LogRecordRows) GetTimes(index int) []time.Time {
+	times := []time.Time{}
+	for _, record := range r {
+		times = append(times, record[index].(time.Time))
+	}
+
+	return times
+}
+
+// Summarize applies the queries to each group.
+// If prev is given, the groups only in prev are returned as Gone.
+func (r LogRecords) Summarize(queries []Query, prev *LogRecords, test SignificanceTest) (SummaryRecords, error) {
 	summary := map[string][]SummaryRowCell{}
 	resultTypes := map[string]LogRecordType{}
 	for key, records := range r.Records {
@@ -101,15 +259,51 @@ func (r LogRecords) Summarize(queries []Query, prevRows map[string]LogRecordRows
 		summary[key] = row
 	}
 
+	prevRows := map[string]LogRecordRows{}
+	if prev != nil {
+		prevRows = prev.Records
+	}
+
+	gone := map[string][]SummaryRowCell{}
+	groups := map[string][]any{}
+	maps.Copy(groups, r.Groups)
 	for prevKey, prevRow := range prevRows {
+		// p-values only depend on the column and the filter
+		pValues := map[string]*float64{}
+
+		if _, ok := summary[prevKey]; !ok {
+			gone[prevKey] = []SummaryRowCell{}
+			groups[prevKey] = prev.Groups[prevKey]
+		}
+
 		for k, query := range queries {
-			value, _, err := query.Apply(r.Columns, prevRow)
+			value, resultType, err := query.Apply(r.Columns, prevRow)
 			if err != nil {
 				return SummaryRecords{}, fmt.Errorf("Failed to apply query: %v (cause: %w)", query, err)
 			}
 
-			if row, ok := summary[prevKey]; ok {
+			if row, ok := gone[prevKey]; ok {
+				gone[prevKey] = append(row, SummaryRowCell{
+					PrevValue: value,
+				})
+				resultTypes[query.Name] = cmp.Or(resultTypes[query.Name], resultType)
+			} else if row, ok := summary[prevKey]; ok {
 				row[k].PrevValue = value
+
+				pValueKey := fmt.Sprintf("%v/%v", query.From, query.Filter)
+				pValue, ok := pValues[pValueKey]
+				if !ok {
+					pValue, err = query.PValue(test, r.Columns, r.Records[prevKey], prevRow)
+					if err != nil {
+						return SummaryRecords{}, fmt.Errorf("Failed to test significance: %v (cause: %w)", query, err)
+					}
+
+					if pValue != nil {
+						pValues[pValueKey] = pValue
+					}
+				}
+
+				row[k].PValue = pValue
 			}
 		}
 	}
@@ -123,7 +317,68 @@ func (r LogRecords) Summarize(queries []Query, prevRows map[string]LogRecordRows
 	}
 
 	return SummaryRecords{
-		Columns: columns,
-		Rows:    summary,
+		Columns:      columns,
+		Rows:         summary,
+		HasPrev:      prev != nil,
+		Gone:         gone,
+		GroupingKeys: r.GroupingKeys,
+		Groups:       groups,
 	}, nil
 }
+
+const OtherGroupKey = "(other)"
+
+func OtherGroupLabel(groups int) string {
+	return fmt.Sprintf("(other %d groups)", groups)
+}
+
+// SummarizeOther computes a single row over the combined records of the given keys.
+// The last string column is used for the label.
+func (r LogRecords) SummarizeOther(queries []Query, keys []string, prevRows map[string]LogRecordRows, test SignificanceTest) ([]SummaryRowCell, error) {
+	records := LogRecordRows{}
+	prevRecords := LogRecordRows{}
+	for _, key := range keys {
+		records = append(records, r.Records[key]...)
+		prevRecords = append(prevRecords, prevRows[key]...)
+	}
+
+	labelIndex := -1
+	row := []SummaryRowCell{}
+	for _, query := range queries {
+		value, resultType, err := query.Apply(r.Columns, records)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to apply query: %v (cause: %w)", query, err)
+		}
+
+		var prevValue any
+		var pValue *float64
+		if len(prevRecords) > 0 {
+			prevValue, _, err = query.Apply(r.Columns, prevRecords)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to apply query: %v (cause: %w)", query, err)
+			}
+
+			pValue, err = query.PValue(test, r.Columns, records, prevRecords)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to test significance: %v (cause: %w)", query, err)
+			}
+		}
+
+		if resultType == LogRecordTypeString && query.Function == QueryFunctionAny {
+			value, prevValue = "", nil
+			labelIndex = len(row)
+		}
+
+		row = append(row, SummaryRowCell{
+			Value:     value,
+			PrevValue: prevValue,
+			PValue:    pValue,
+		})
+	}
+
+	if labelIndex >= 0 {
+		row[labelIndex].Value = OtherGroupLabel(len(keys))
+	}
+
+	return row, nil
+}
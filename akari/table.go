@@ -5,7 +5,9 @@ import (
 	"html/template"
 	"io"
 	"math"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -15,33 +17,40 @@ const (
 
 type TableColumn struct {
 	Name      string
+	Type      LogRecordType
 	Alignment string
 }
 
 type TableCell struct {
-	Value        string
-	RawValue     any
-	PrevRawValue any
-	Alignment    string
+	Value         string
+	RawValue      any
+	PrevRawValue  any
+	PValue        *float64
+	Insignificant bool
+	Alignment     string
 }
 
 func (c TableCell) Diff() float64 {
-	if c.PrevRawValue == nil {
+	return RelativeDiff(c.RawValue, c.PrevRawValue)
+}
+
+func RelativeDiff(value any, prevValue any) float64 {
+	if prevValue == nil {
 		return 0
 	}
 
-	switch c.RawValue.(type) {
+	switch value.(type) {
 	case int:
-		v := c.RawValue.(int)
-		p := c.PrevRawValue.(int)
+		v := value.(int)
+		p := prevValue.(int)
 		if p == 0 {
 			return 0
 		}
 
 		return float64(v-p) / float64(p)
 	case float64:
-		v := c.RawValue.(float64)
-		p := c.PrevRawValue.(float64)
+		v := value.(float64)
+		p := prevValue.(float64)
 		if p < 0.1 {
 			return 0
 		}
@@ -53,13 +62,67 @@ func (c TableCell) Diff() float64 {
 }
 
 type TableRow struct {
-	Key   string
-	Cells []TableCell
+	Key    string
+	Group  []any
+	Status GroupStatus
+	Cells  []TableCell
+}
+
+type GroupValue struct {
+	Name  string
+	Value any
+}
+
+func GroupValues(keys []string, values []any) []GroupValue {
+	result := []GroupValue{}
+	for i, key := range keys {
+		if i < len(values) {
+			result = append(result, GroupValue{
+				Name:  key,
+				Value: values[i],
+			})
+		}
+	}
+
+	return result
 }
 
 type TableData struct {
-	Columns []TableColumn
-	Rows    []TableRow
+	Columns      []TableColumn
+	GroupingKeys []string
+	Rows         []TableRow
+	// Rows which exist only in the previous file
+	Gone     []TableRow
+	Outliers *Outliers `json:",omitempty"`
+}
+
+func (d TableData) GoneTable() TableData {
+	return TableData{
+		Columns:      d.Columns,
+		GroupingKeys: d.GroupingKeys,
+		Rows:         d.Gone,
+	}
+}
+
+func diffText(value float64, insignificant bool) string {
+	if math.Abs(value) < 0.01 {
+		return ""
+	}
+	if insignificant {
+		return fmt.Sprintf("(~%+d%%)", int(value*100))
+	}
+
+	return fmt.Sprintf("(%+d%%)", int(value*100))
+}
+
+func rankText(value int, prevValue int) string {
+	if value > prevValue {
+		return fmt.Sprintf("(↘︎%d)", value-prevValue)
+	} else if value < prevValue {
+		return fmt.Sprintf("(↗︎%d)", prevValue-value)
+	}
+
+	return ""
 }
 
 func (d TableData) Write(w io.Writer) {
@@ -106,6 +169,163 @@ func (d TableData) Write(w io.Writer) {
 	}
 }
 
+type TextOptions struct {
+	ShowRank    bool
+	DiffHeaders []string
+	Color       bool
+	// Columns whose increase is an improvement. Increases of the other columns (e.g. latency, errors) are shown as regressions.
+	HigherIsBetter []string
+	// Adds a column showing whether the group is new
+	ShowStatus bool
+}
+
+func (o TextOptions) IsDiffHeader(header string) bool {
+	return slices.Contains(o.DiffHeaders, header)
+}
+
+type textCell struct {
+	Text  string
+	Right bool
+	// 1 if the change is an improvement, -1 if it is a regression
+	Change int
+	Dim    bool
+}
+
+const (
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiReset = "\x1b[0m"
+)
+
+func sign(value float64) int {
+	if value > 0 {
+		return 1
+	} else if value < 0 {
+		return -1
+	}
+
+	return 0
+}
+
+// change tells whether the difference of the column is an improvement (1) or a regression (-1)
+func (o TextOptions) change(column string, diff float64) int {
+	if slices.Contains(o.HigherIsBetter, column) {
+		return sign(diff)
+	}
+
+	return -sign(diff)
+}
+
+func (d TableData) WriteText(w io.Writer, options TextOptions) {
+	table := [][]textCell{}
+
+	headers := []textCell{}
+	for i, column := range d.Columns {
+		right := column.Alignment == TableColumnAlignmentRight
+		headers = append(headers, textCell{Text: column.Name, Right: right})
+
+		if options.IsDiffHeader(column.Name) {
+			headers = append(headers, textCell{Text: "(diff)"})
+		}
+		if options.ShowRank && i == 0 {
+			headers = append(headers, textCell{})
+		}
+	}
+	if options.ShowStatus {
+		headers = append(headers, textCell{})
+	}
+
+	table = append(table, headers)
+
+	for _, row := range d.Rows {
+		tableRow := []textCell{}
+		for i, column := range d.Columns {
+			cell := row.Cells[i]
+			tableRow = append(tableRow, textCell{
+				Text:  cell.Value,
+				Right: column.Alignment == TableColumnAlignmentRight,
+			})
+
+			if options.IsDiffHeader(column.Name) {
+				value := cell.Diff()
+				text := diffText(value, cell.Insignificant)
+				change := 0
+				if text != "" {
+					change = options.change(column.Name, value)
+					tableRow[len(tableRow)-1].Change = change
+					tableRow[len(tableRow)-1].Dim = cell.Insignificant
+				}
+
+				tableRow = append(tableRow, textCell{
+					Text:   text,
+					Change: change,
+					Dim:    cell.Insignificant,
+				})
+			}
+			if options.ShowRank && i == 0 {
+				value, _ := cell.RawValue.(int)
+				prevValue, _ := cell.PrevRawValue.(int)
+				if prevValue == 0 {
+					tableRow = append(tableRow, textCell{})
+					continue
+				}
+
+				// the rows are sorted by the sort keys, so climbing up means the group got heavier
+				tableRow = append(tableRow, textCell{
+					Text:   rankText(value, prevValue),
+					Change: -sign(float64(prevValue - value)),
+				})
+			}
+		}
+		if options.ShowStatus {
+			text := ""
+			if row.Status != "" {
+				text = fmt.Sprintf("(%v)", row.Status)
+			}
+
+			tableRow = append(tableRow, textCell{Text: text})
+		}
+
+		table = append(table, tableRow)
+	}
+
+	widths := make([]int, len(headers))
+	for _, row := range table {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell.Text))
+		}
+	}
+
+	for _, row := range table {
+		line := strings.Builder{}
+		for i, cell := range row {
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell.Text))
+			text := cell.Text
+			if options.Color && cell.Dim {
+				text = ansiDim + text + ansiReset
+			} else if options.Color && cell.Change != 0 {
+				color := ansiGreen
+				if cell.Change < 0 {
+					color = ansiRed
+				}
+
+				text = color + text + ansiReset
+			}
+
+			if cell.Right {
+				line.WriteString(padding + text)
+			} else {
+				line.WriteString(text + padding)
+			}
+			if i < len(row)-1 {
+				line.WriteString("  ")
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+}
+
 type HtmlOptions struct {
 	ShowRank    bool
 	DiffHeaders []string
@@ -128,16 +348,26 @@ func (d TableData) Html(options HtmlOptions) HtmlTableData {
 			style["text-align"] = column.Alignment
 		}
 
+		// the diff and rank columns belong to the column, so that they are shown or hidden together
+		attrs := map[string]string{
+			"data-column": column.Name,
+		}
+		if slices.Contains(d.GroupingKeys, column.Name) {
+			attrs["data-grouping"] = "true"
+		}
+
 		headers = append(headers, HtmlTableHeader{
-			Text:  column.Name,
-			Style: style,
+			Text:       column.Name,
+			Attributes: attrs,
+			Style:      style,
 		})
 
 		if options.IsDiffHeader(column.Name) {
 			headers = append(headers, HtmlTableHeader{
 				Text: "(diff)",
 				Attributes: map[string]string{
-					"data-diff": "true",
+					"data-diff":   "true",
+					"data-column": column.Name,
 				},
 			})
 		}
@@ -145,7 +375,8 @@ func (d TableData) Html(options HtmlOptions) HtmlTableData {
 			headers = append(headers, HtmlTableHeader{
 				Text: "",
 				Attributes: map[string]string{
-					"data-diff": "true",
+					"data-diff":   "true",
+					"data-column": column.Name,
 				},
 				Style: style,
 			})
@@ -169,24 +400,31 @@ func (d TableData) Html(options HtmlOptions) HtmlTableData {
 				attrs["data-prev-value"] = fmt.Sprintf("%v", cell.PrevRawValue)
 			}
 
+			// the values come from the logs, so they are escaped before keeping the spaces
 			htmlRow = append(htmlRow, HtmlTableCell{
-				Text:       template.HTML(strings.ReplaceAll(cell.Value, " ", "&nbsp;")),
+				Text:       template.HTML(strings.ReplaceAll(template.HTMLEscapeString(cell.Value), " ", "&nbsp;")),
 				Attributes: attrs,
 				Style:      style,
 			})
 
 			if options.IsDiffHeader(d.Columns[i].Name) {
 				value := cell.Diff()
-				if math.Abs(value) < 0.01 {
+				if text := diffText(value, cell.Insignificant); text == "" {
 					htmlRow = append(htmlRow, HtmlTableCell{
 						Text: template.HTML(""),
 					})
 				} else {
+					attrs := map[string]string{
+						"data-value": fmt.Sprintf("%v", value),
+					}
+					if cell.Insignificant {
+						attrs["data-insignificant"] = "true"
+						attrs["title"] = fmt.Sprintf("p=%.3f", *cell.PValue)
+					}
+
 					htmlRow = append(htmlRow, HtmlTableCell{
-						Text: template.HTML(fmt.Sprintf("(%+d%%)", int(value*100))),
-						Attributes: map[string]string{
-							"data-value": fmt.Sprintf("%v", value),
-						},
+						Text:       template.HTML(text),
+						Attributes: attrs,
 					})
 				}
 			}
@@ -200,15 +438,8 @@ func (d TableData) Html(options HtmlOptions) HtmlTableData {
 					continue
 				}
 
-				text := ""
-				if value > prevValue {
-					text = fmt.Sprintf("(↘︎%d)", value-prevValue)
-				} else if value < prevValue {
-					text = fmt.Sprintf("(↗︎%d)", prevValue-value)
-				}
-
 				htmlRow = append(htmlRow, HtmlTableCell{
-					Text: template.HTML(text),
+					Text: template.HTML(rankText(value, prevValue)),
 					Attributes: map[string]string{
 						// FIXME: ここのdivisorは適当。あまりに小さい数字にすると表示が変わらないため。
 						"data-value": fmt.Sprintf("%v", float64(prevValue-value)/float64(len(d.Rows)/6)),
@@ -217,8 +448,9 @@ func (d TableData) Html(options HtmlOptions) HtmlTableData {
 			}
 		}
 		rows = append(rows, HtmlTableRow{
-			Key:   row.Key,
-			Cells: htmlRow,
+			Key:    row.Key,
+			Status: row.Status,
+			Cells:  htmlRow,
 		})
 	}
 
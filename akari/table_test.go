@@ -0,0 +1,59 @@
+package akari
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	result := testAnalyze(t, testAnalyzerConfig(), "GET /a 2.0\nGET /long 1.0\n", "")
+
+	buffer := bytes.NewBuffer(nil)
+	result.Write(buffer)
+
+	// left-aligned columns are not padded
+	assert.Equal(t, "Count  Total  Method  Url\n    1  2.000  GET  /a\n    1  1.000  GET  /long\n", buffer.String())
+}
+
+func TestWriteTextColor(t *testing.T) {
+	config := testAnalyzerConfig()
+	config.ShowRank = true
+	config.Diffs = []string{"Count", "Total"}
+
+	result := testAnalyze(t, config, "GET /a 1.0\nGET /b 2.0\nGET /b 1.0\n", "GET /a 4.0\nGET /b 1.0\n")
+
+	buffer := bytes.NewBuffer(nil)
+	result.WriteText(buffer, TextOptions{
+		ShowRank:       true,
+		DiffHeaders:    config.Diffs,
+		HigherIsBetter: []string{"Count"},
+		Color:          true,
+	})
+	lines := bytes.Split(buffer.Bytes(), []byte("\n"))
+
+	// /b climbed up the ranking, and its Total increased: both are regressions
+	assert.Contains(t, string(lines[1]), ansiRed+"(↗︎1)"+ansiReset)
+	assert.Contains(t, string(lines[1]), ansiRed+"(+200%)"+ansiReset)
+	// but the increase of Count is an improvement
+	assert.Contains(t, string(lines[1]), ansiGreen+"(+100%)"+ansiReset)
+
+	// /a went down and got faster
+	assert.Contains(t, string(lines[2]), ansiGreen+"(↘︎1)"+ansiReset)
+	assert.Contains(t, string(lines[2]), ansiGreen+"(-75%)"+ansiReset)
+}
+
+func TestTableDataHtml(t *testing.T) {
+	data := testTableData()
+	data.GroupingKeys = []string{"Url"}
+	data.Rows[0].Cells[2].Value = `<script>alert("a b")</script>`
+
+	html := data.Html(HtmlOptions{})
+
+	assert.Equal(t, map[string]string{"data-column": "Count"}, html.Headers[0].Attributes)
+	assert.Equal(t, map[string]string{"data-column": "Url", "data-grouping": "true"}, html.Headers[2].Attributes)
+
+	assert.Equal(t, template.HTML("&lt;script&gt;alert(&#34;a&nbsp;b&#34;)&lt;/script&gt;"), html.Rows[0].Cells[2].Text)
+}
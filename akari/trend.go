@@ -0,0 +1,163 @@
+package akari
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+type TrendRun struct {
+	Name    string
+	Summary SummaryRecords
+	// benchmark score of the run (nil if not recorded)
+	Score *float64
+}
+
+// Trend collects the row of the group from each run, in the given order.
+// Each row is compared with the previous run which has the group. Runs without the group are skipped.
+func Trend(config AnalyzerConfig, runs []TrendRun, key string) (TableData, error) {
+	formatOptions, err := config.FormatOptions()
+	if err != nil {
+		return TableData{}, fmt.Errorf("Failed to prepare format options (%w)", err)
+	}
+
+	records := SummaryRecordKeyPairs{}
+	var prev []SummaryRowCell
+	for _, run := range runs {
+		row, ok := run.Summary.Rows[key]
+		if !ok {
+			continue
+		}
+
+		cells := []SummaryRowCell{}
+		for i, cell := range row {
+			var prevValue any
+			if prev != nil {
+				prevValue = prev[i].Value
+			}
+
+			cells = append(cells, SummaryRowCell{
+				Value:     cell.Value,
+				PrevValue: prevValue,
+			})
+		}
+
+		records.Columns = run.Summary.Columns
+		records.GroupingKeys = run.Summary.GroupingKeys
+		records.Entries = append(records.Entries, SummaryRecordKeyPair{
+			Key:    run.Name,
+			Group:  run.Summary.Groups[key],
+			Record: cells,
+		})
+		prev = row
+	}
+
+	if len(records.Entries) == 0 {
+		return TableData{}, fmt.Errorf("Group not found in any run: %v", key)
+	}
+
+	result := records.Format(formatOptions)
+	result.Columns = slices.Insert(result.Columns, 0, TableColumn{
+		Name:      "Run",
+		Type:      LogRecordTypeString,
+		Alignment: TableColumnAlignmentLeft,
+	})
+	for i, row := range result.Rows {
+		result.Rows[i].Cells = slices.Insert(row.Cells, 0, TableCell{
+			Value:     row.Key,
+			RawValue:  row.Key,
+			Alignment: TableColumnAlignmentLeft,
+		})
+	}
+
+	return result, nil
+}
+
+// Series returns the values of the numeric column for each row, labeled by the first column
+func (d TableData) Series(column string) ([]ChartPoint, bool) {
+	index := d.columnIndex(column)
+	if index < 0 || !d.Columns[index].Type.IsNumeric() {
+		return nil, false
+	}
+
+	points := []ChartPoint{}
+	for _, row := range d.Rows {
+		value, ok := NumericValue(row.Cells[index].RawValue)
+		if !ok {
+			continue
+		}
+
+		points = append(points, ChartPoint{
+			Label: row.Cells[0].Value,
+			Value: value,
+		})
+	}
+
+	return points, true
+}
+
+type ScoreCorrelation struct {
+	Key   string
+	Group []any
+	// Pearson correlation coefficient between the column and the score
+	Correlation float64
+	Runs        int
+}
+
+// CorrelateScore computes the correlation between the column of each group and the score across the scored runs.
+// Groups found in less than minRuns runs are skipped. The result is sorted by the strength of the correlation.
+func CorrelateScore(runs []TrendRun, column string, minRuns int) ([]ScoreCorrelation, error) {
+	values := map[string][]float64{}
+	scores := map[string][]float64{}
+	groups := map[string][]any{}
+	for _, run := range runs {
+		if run.Score == nil {
+			continue
+		}
+
+		index := run.Summary.GetIndex(column)
+		if index < 0 {
+			return nil, fmt.Errorf("Unknown column: %v", column)
+		}
+
+		for key, row := range run.Summary.Rows {
+			value, ok := NumericValue(row[index].Value)
+			if !ok || math.IsNaN(value) {
+				continue
+			}
+
+			values[key] = append(values[key], value)
+			scores[key] = append(scores[key], *run.Score)
+			groups[key] = run.Summary.Groups[key]
+		}
+	}
+
+	result := []ScoreCorrelation{}
+	for key, xs := range values {
+		if len(xs) < minRuns {
+			continue
+		}
+
+		correlation, ok := PearsonCorrelation(xs, scores[key])
+		if !ok {
+			continue
+		}
+
+		result = append(result, ScoreCorrelation{
+			Key:         key,
+			Group:       groups[key],
+			Correlation: correlation,
+			Runs:        len(xs),
+		})
+	}
+
+	slices.SortFunc(result, func(a, b ScoreCorrelation) int {
+		return cmp.Or(
+			cmp.Compare(math.Abs(b.Correlation), math.Abs(a.Correlation)),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package akari
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrend(t *testing.T) {
+	config := testAnalyzerConfig()
+
+	runs := []TrendRun{}
+	for i, source := range []string{
+		"GET /a 1.0\nGET /a 1.0\n",
+		"GET /b 1.0\n",
+		"GET /a 0.5\n",
+	} {
+		summary, err := Summarize(AnalyzeOptions{
+			Config: config,
+			Source: strings.NewReader(source),
+			Logger: slog.Default(),
+		})
+		assert.NoError(t, err)
+
+		runs = append(runs, TrendRun{Name: string(rune('1' + i)), Summary: summary})
+	}
+
+	key, err := GroupKey([]any{"GET", "/a"})
+	assert.NoError(t, err)
+
+	trend, err := Trend(config, runs, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, columnValues(trend, 0))
+	assert.Equal(t, []string{"2.000", "0.500"}, columnValues(trend, 2))
+	assert.Equal(t, -0.75, trend.Rows[1].Cells[2].Diff())
+
+	points, ok := trend.Series("Count")
+	assert.True(t, ok)
+	assert.Equal(t, []ChartPoint{{Label: "1", Value: 2}, {Label: "3", Value: 1}}, points)
+
+	_, ok = trend.Series("Method")
+	assert.False(t, ok)
+
+	_, err = Trend(config, runs, "unknown")
+	assert.Error(t, err)
+}
+
+func TestCorrelateScore(t *testing.T) {
+	config := testAnalyzerConfig()
+
+	runs := []TrendRun{}
+	for i, source := range []string{
+		"GET /a 1.0\nGET /b 1.0\n",
+		"GET /a 2.0\nGET /b 1.5\n",
+		"GET /a 3.0\nGET /b 1.0\n",
+		"GET /a 9.0\nGET /b 9.0\n",
+	} {
+		summary, err := Summarize(AnalyzeOptions{
+			Config: config,
+			Source: strings.NewReader(source),
+			Logger: slog.Default(),
+		})
+		assert.NoError(t, err)
+
+		score := float64(300 - i*100)
+		run := TrendRun{Name: string(rune('1' + i)), Summary: summary, Score: &score}
+		// the last run has no score, and is ignored
+		if i == 3 {
+			run.Score = nil
+		}
+		runs = append(runs, run)
+	}
+
+	correlations, err := CorrelateScore(runs, "Total", 3)
+	assert.NoError(t, err)
+	assert.Len(t, correlations, 2)
+	assert.Equal(t, []any{"GET", "/a"}, correlations[0].Group)
+	assert.InDelta(t, -1.0, correlations[0].Correlation, 1e-9)
+	assert.Equal(t, 3, correlations[0].Runs)
+	assert.InDelta(t, 0.0, correlations[1].Correlation, 1e-9)
+
+	correlations, err = CorrelateScore(runs, "Total", 4)
+	assert.NoError(t, err)
+	assert.Empty(t, correlations)
+
+	_, err = CorrelateScore(runs, "Unknown", 3)
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/myuon/akari/akari"
+)
+
+var ErrAssertionFailed = errors.New("assertion failed")
+
+// checkAssertions checks the assertions in the analyzer config and the given rules, and writes the violations
+func checkAssertions(analyzer akari.AnalyzerConfig, result akari.TableData, rules []string, w io.Writer) error {
+	assertions, err := akari.ParseAssertions(append(slices.Clone(analyzer.Assertions), rules...))
+	if err != nil {
+		return fmt.Errorf("failed to parse assertions: %w", err)
+	}
+
+	violations, err := result.Check(assertions)
+	if err != nil {
+		return fmt.Errorf("failed to check assertions: %w", err)
+	}
+
+	if len(violations) > 0 {
+		if w == nil {
+			w = os.Stderr
+		}
+
+		akari.WriteViolations(w, violations)
+		return fmt.Errorf("%w: %d violations", ErrAssertionFailed, len(violations))
+	}
+
+	return nil
+}
+
+func loadConfig(path string) (akari.AkariConfig, error) {
+	var config akari.AkariConfig
+	if _, err := toml.DecodeFile(path, &config); err != nil {
+		return akari.AkariConfig{}, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return config, nil
+}
+
+// peekLine reads the first line of the file and rewinds it
+func peekLine(file *os.File) ([]byte, error) {
+	line := make([]byte, 512)
+	n, err := file.Read(line)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	}
+	line = line[:n]
+
+	if strings.Contains(string(line), "\n") {
+		line = []byte(strings.SplitN(string(line), "\n", 2)[0])
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return nil, fmt.Errorf("failed to rewind log file: %w", err)
+	}
+
+	return line, nil
+}
+
+func detectAnalyzer(config akari.AkariConfig, line []byte) (akari.AnalyzerConfig, bool) {
+	for _, analyzer := range config.Analyzers {
+		if analyzer.Parser.RegExp.Match(line) {
+			return analyzer, true
+		}
+	}
+
+	return akari.AnalyzerConfig{}, false
+}
+
+func findAnalyzer(config akari.AkariConfig, logType string) (akari.AnalyzerConfig, bool) {
+	for _, analyzer := range config.Analyzers {
+		if analyzer.Name == logType {
+			return analyzer, true
+		}
+	}
+
+	return akari.AnalyzerConfig{}, false
+}
+
+// analyzeFiles runs the analyzer against the opened log file, and also against the previous file if it is given
+func analyzeFiles(analyzer akari.AnalyzerConfig, logFile io.Reader, prevFilePath string, logger akari.DebugLogger) (akari.TableData, error) {
+	hasPrev := prevFilePath != ""
+	var prev io.Reader
+	if hasPrev {
+		prevFile, err := os.Open(prevFilePath)
+		if err != nil {
+			return akari.TableData{}, fmt.Errorf("failed to open previous log file: %w", err)
+		}
+		defer prevFile.Close()
+
+		prev = prevFile
+	}
+
+	result, err := akari.Analyze(akari.AnalyzeOptions{
+		Config:  analyzer,
+		Source:  logFile,
+		HasPrev: hasPrev,
+		Prev:    prev,
+		Logger:  logger,
+	})
+	if err != nil {
+		return akari.TableData{}, fmt.Errorf("failed to analyze: %w", err)
+	}
+
+	return result, nil
+}
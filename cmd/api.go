@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/myuon/akari/akari"
+)
+
+type ApiRun struct {
+	ID         string
+	Path       string
+	DirPath    string
+	Name       string
+	LogType    string
+	PrevID     string
+	PrevPath   string
+	IsBaseline bool
+	ModifiedAt time.Time
+	Size       int64
+	Meta       ApiRunMeta
+}
+
+// ApiRunMeta is RunMeta in the casing of the API, apart from the format of the sidecar files
+type ApiRunMeta struct {
+	Commit string   `json:",omitempty"`
+	Branch string   `json:",omitempty"`
+	Score  *float64 `json:",omitempty"`
+	Notes  string   `json:",omitempty"`
+}
+
+func newApiRunMeta(meta RunMeta) ApiRunMeta {
+	return ApiRunMeta{
+		Commit: meta.Commit,
+		Branch: meta.Branch,
+		Score:  meta.Score,
+		Notes:  meta.Notes,
+	}
+}
+
+type ApiFilterResult struct {
+	Columns akari.LogRecordColumns
+	Group   []akari.GroupValue
+	Records akari.LogRecordRows
+}
+
+type ApiError struct {
+	Error string
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	// encode first, so that a value which cannot be encoded is not sent as a truncated body with the status
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(value); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	if _, err := w.Write(body.Bytes()); err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ApiError{Error: message})
+}
+
+func writeJSONRunError(w http.ResponseWriter, err error) {
+	status := runErrorStatus(err)
+	writeJSONError(w, status, http.StatusText(status))
+}
+
+// resolveAnalyzer finds the analyzer by the type, or detects it from the file if the type is empty
+func resolveAnalyzer(logType string, filePath string) (akari.AnalyzerConfig, bool) {
+	if logType == "" {
+		detected, err := detectLogType(filePath)
+		if err != nil {
+			return akari.AnalyzerConfig{}, false
+		}
+
+		logType = detected
+	}
+
+	return findAnalyzer(config.Load(), logType)
+}
+
+func apiRunsHandler(w http.ResponseWriter, r *http.Request) {
+	serverData := UseServerData(r)
+
+	files, err := listLogFiles(serverData.LogDir, serverData.LogDir)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list files")
+		slog.Error("Failed to list files", "error", err)
+		return
+	}
+
+	logType := r.URL.Query().Get("type")
+
+	runs := []ApiRun{}
+	for _, file := range files {
+		if file.LogType == "unknown" || (logType != "" && file.LogType != logType) {
+			continue
+		}
+
+		runs = append(runs, ApiRun{
+			ID:         file.ID,
+			Path:       runName(serverData.LogDir, file.Path),
+			DirPath:    runName(serverData.LogDir, file.DirPath),
+			Name:       file.Name,
+			LogType:    file.LogType,
+			PrevID:     file.PrevID,
+			PrevPath:   runName(serverData.LogDir, file.PrevPath),
+			IsBaseline: file.IsBaseline,
+			ModifiedAt: file.ModifiedAt,
+			Size:       file.Size,
+			Meta:       newApiRunMeta(readRunMeta(file.DirPath)),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func apiAnalyzeHandler(w http.ResponseWriter, r *http.Request) {
+	serverData := UseServerData(r)
+
+	filePath, err := resolveRun(serverData.LogDir, r.URL.Query().Get("file"))
+	if err != nil {
+		writeJSONRunError(w, err)
+		return
+	}
+
+	prevFilePath := ""
+	if prevID := r.URL.Query().Get("prev"); prevID != "" {
+		prevFilePath, err = resolveRun(serverData.LogDir, prevID)
+		if err != nil {
+			writeJSONRunError(w, err)
+			return
+		}
+	}
+
+	analyzer, ok := resolveAnalyzer(r.URL.Query().Get("type"), filePath)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "Unknown log type")
+		return
+	}
+
+	result, err := serverData.analyze(analyzer, filePath, prevFilePath)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to analyze log")
+		slog.Error("Failed to analyze log", "error", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func apiFilterHandler(w http.ResponseWriter, r *http.Request) {
+	filePath, err := resolveRun(UseServerData(r).LogDir, r.URL.Query().Get("file"))
+	if err != nil {
+		writeJSONRunError(w, err)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeJSONError(w, http.StatusBadRequest, "Key not specified")
+		return
+	}
+
+	analyzer, ok := resolveAnalyzer(r.URL.Query().Get("type"), filePath)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "Unknown log type")
+		return
+	}
+
+	logFile, err := os.Open(filePath)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	defer logFile.Close()
+
+	parseOptions, err := analyzer.ParseOptions()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get parse options")
+		return
+	}
+
+	parsed, err := akari.Parse(parseOptions, logFile, slog.Default())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to analyze log")
+		slog.Error("Failed to parse log", "error", err)
+		return
+	}
+
+	records, ok := parsed.Records[key]
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	// NaN and Inf are not valid JSON numbers
+	rows := akari.LogRecordRows{}
+	for _, record := range records {
+		row := akari.LogRecordRow{}
+		for _, value := range record {
+			if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				value = nil
+			}
+
+			row = append(row, value)
+		}
+
+		rows = append(rows, row)
+	}
+
+	writeJSON(w, http.StatusOK, ApiFilterResult{
+		Columns: parsed.Columns,
+		Group:   akari.GroupValues(parsed.GroupingKeys, parsed.Groups[key]),
+		Records: rows,
+	})
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/myuon/akari/akari"
+	"github.com/stretchr/testify/assert"
+)
+
+func getJSON(t *testing.T, handler http.HandlerFunc, logDir string, target string, value any) int {
+	w := serveTest(handler, logDir, httptest.NewRequest(http.MethodGet, target, nil))
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), value))
+	return w.Code
+}
+
+func TestApiRunsHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 2)
+
+	var runs []ApiRun
+	assert.Equal(t, http.StatusOK, getJSON(t, apiRunsHandler, logDir, "/api/runs", &runs))
+	assert.Len(t, runs, 2)
+	assert.Equal(t, testRunID(logDir, "1"), runs[0].ID)
+	assert.Empty(t, runs[0].PrevID)
+	assert.Equal(t, testRunID(logDir, "2"), runs[1].ID)
+	assert.Equal(t, "2/access.log", runs[1].Path)
+	assert.Equal(t, "nginx", runs[1].LogType)
+	assert.Equal(t, testRunID(logDir, "1"), runs[1].PrevID)
+	assert.Equal(t, "1/access.log", runs[1].PrevPath)
+
+	assert.Equal(t, http.StatusOK, getJSON(t, apiRunsHandler, logDir, "/api/runs?type=mysql", &runs))
+	assert.Empty(t, runs)
+
+	// the metadata has the same casing as the rest of the API, whatever the sidecar file is
+	score := 1200.0
+	assert.NoError(t, saveRunMeta(filepath.Join(logDir, "2"), RunMeta{Commit: "abc123", Score: &score}))
+
+	var raw []map[string]any
+	assert.Equal(t, http.StatusOK, getJSON(t, apiRunsHandler, logDir, "/api/runs", &raw))
+	assert.Equal(t, map[string]any{"Commit": "abc123", "Score": 1200.0}, raw[1]["Meta"])
+	assert.Equal(t, map[string]any{}, raw[0]["Meta"])
+}
+
+func TestApiAnalyzeHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 2)
+
+	var result akari.TableData
+	query := url.Values{"file": {testRunID(logDir, "2")}, "prev": {testRunID(logDir, "1")}}
+	assert.Equal(t, http.StatusOK, getJSON(t, apiAnalyzeHandler, logDir, "/api/analyze?"+query.Encode(), &result))
+	assert.Equal(t, "Count", result.Columns[1].Name)
+	assert.Len(t, result.Rows, 2)
+	assert.NotEmpty(t, result.Rows[0].Key)
+
+	var apiError ApiError
+	query = url.Values{"file": {"unknown"}}
+	assert.Equal(t, http.StatusNotFound, getJSON(t, apiAnalyzeHandler, logDir, "/api/analyze?"+query.Encode(), &apiError))
+	assert.Equal(t, "Not Found", apiError.Error)
+
+	query = url.Values{"file": {testRunID(logDir, "2")}, "prev": {"unknown"}}
+	assert.Equal(t, http.StatusNotFound, getJSON(t, apiAnalyzeHandler, logDir, "/api/analyze?"+query.Encode(), &apiError))
+
+	query = url.Values{"file": {testRunID(logDir, "2")}, "type": {"unknown"}}
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, apiAnalyzeHandler, logDir, "/api/analyze?"+query.Encode(), &apiError))
+	assert.Equal(t, "Unknown log type", apiError.Error)
+
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, apiAnalyzeHandler, logDir, "/api/analyze", &apiError))
+}
+
+func TestApiAnalyzeHandlerInf(t *testing.T) {
+	logDir := setupTestLogDir(t, 1)
+	logPath := filepath.Join(logDir, "1", "access.log")
+	assert.NoError(t, os.WriteFile(logPath, []byte(strings.ReplaceAll(testNginxLog, "0.110", "+Inf")), 0o644))
+
+	c := config.Load()
+	c.Analyzers = slices.Clone(c.Analyzers)
+	c.Analyzers[0].Outliers = &akari.OutliersConfig{Count: 5}
+	config.Store(c)
+
+	// Inf is not a valid JSON number, and the whole result is still encoded
+	var result akari.TableData
+	query := url.Values{"file": {testRunID(logDir, "1")}}
+	assert.Equal(t, http.StatusOK, getJSON(t, apiAnalyzeHandler, logDir, "/api/analyze?"+query.Encode(), &result))
+	assert.Len(t, result.Rows, 2)
+	assert.NotNil(t, result.Outliers)
+}
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusOK, map[string]float64{"Value": math.Inf(1)})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"Error":"Failed to encode response"}`, w.Body.String())
+}
+
+func TestApiFilterHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 1)
+
+	var analyzed akari.TableData
+	query := url.Values{"file": {testRunID(logDir, "1")}}
+	assert.Equal(t, http.StatusOK, getJSON(t, apiAnalyzeHandler, logDir, "/api/analyze?"+query.Encode(), &analyzed))
+
+	var result ApiFilterResult
+	query = url.Values{"file": {testRunID(logDir, "1")}, "key": {analyzed.Rows[0].Key}}
+	assert.Equal(t, http.StatusOK, getJSON(t, apiFilterHandler, logDir, "/api/filter?"+query.Encode(), &result))
+	assert.NotEmpty(t, result.Columns)
+	assert.NotEmpty(t, result.Group)
+	assert.Len(t, result.Records, 1)
+	assert.Len(t, result.Records[0], len(result.Columns))
+
+	var apiError ApiError
+	query = url.Values{"file": {testRunID(logDir, "1")}, "key": {"unknown"}}
+	assert.Equal(t, http.StatusNotFound, getJSON(t, apiFilterHandler, logDir, "/api/filter?"+query.Encode(), &apiError))
+	assert.Equal(t, "Group not found", apiError.Error)
+
+	query = url.Values{"file": {testRunID(logDir, "1")}}
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, apiFilterHandler, logDir, "/api/filter?"+query.Encode(), &apiError))
+	assert.Equal(t, "Key not specified", apiError.Error)
+
+	query = url.Values{"file": {testRunID(logDir, "1")}, "key": {analyzed.Rows[0].Key}, "type": {"unknown"}}
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, apiFilterHandler, logDir, "/api/filter?"+query.Encode(), &apiError))
+
+	query = url.Values{"file": {"unknown"}, "key": {analyzed.Rows[0].Key}}
+	assert.Equal(t, http.StatusNotFound, getJSON(t, apiFilterHandler, logDir, "/api/filter?"+query.Encode(), &apiError))
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/myuon/akari/akari"
+)
+
+type accessLevel int
+
+const (
+	accessNone accessLevel = iota
+	accessReadOnly
+	accessFull
+)
+
+// authConfig is the auth config in the config file, overridden by the environment variables
+func authConfig() akari.ServerAuthConfig {
+	return envAuthConfig(config.Load().Server.Auth)
+}
+
+func envAuthConfig(auth akari.ServerAuthConfig) akari.ServerAuthConfig {
+	auth.Username = akari.StringOr(os.Getenv("AKARI_AUTH_USERNAME"), auth.Username)
+	auth.Password = akari.StringOr(os.Getenv("AKARI_AUTH_PASSWORD"), auth.Password)
+	auth.Token = akari.StringOr(os.Getenv("AKARI_AUTH_TOKEN"), auth.Token)
+	auth.ReadOnlyToken = akari.StringOr(os.Getenv("AKARI_AUTH_READ_ONLY_TOKEN"), auth.ReadOnlyToken)
+
+	return auth
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func authorize(auth akari.ServerAuthConfig, r *http.Request) accessLevel {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if auth.Token != "" && secureEqual(token, auth.Token) {
+			return accessFull
+		}
+		if auth.ReadOnlyToken != "" && secureEqual(token, auth.ReadOnlyToken) {
+			return accessReadOnly
+		}
+
+		return accessNone
+	}
+
+	if username, password, ok := r.BasicAuth(); ok && auth.Username != "" {
+		if secureEqual(username, auth.Username) && secureEqual(password, auth.Password) {
+			return accessFull
+		}
+	}
+
+	return accessNone
+}
+
+// withAuth protects every handler if any of the credentials is configured.
+// The read-only token can only GET the JSON API.
+func withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := authConfig()
+		if !auth.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch authorize(auth, r) {
+		case accessFull:
+			next.ServeHTTP(w, r)
+		case accessReadOnly:
+			if (r.Method == http.MethodGet || r.Method == http.MethodHead) && strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		default:
+			if auth.Username != "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="akari", charset="UTF-8"`)
+			}
+
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+	})
+}
+
+// loadServerConfig loads the config, and rejects the auth config which would leave the server unprotected
+func loadServerConfig(path string) (akari.AkariConfig, error) {
+	c, err := loadConfig(path)
+	if err != nil {
+		return akari.AkariConfig{}, err
+	}
+
+	if err := envAuthConfig(c.Server.Auth).Validate(); err != nil {
+		return akari.AkariConfig{}, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	return c, nil
+}
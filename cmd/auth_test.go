@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/myuon/akari/akari"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAuth(t *testing.T) {
+	config.Store(akari.AkariConfig{
+		Server: akari.ServerConfig{
+			Auth: akari.ServerAuthConfig{
+				Username:      "akari",
+				Password:      "secret",
+				Token:         "token",
+				ReadOnlyToken: "read-only",
+			},
+		},
+	})
+	t.Cleanup(func() { config.Store(akari.AkariConfig{}) })
+
+	handler := withAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		setup    func(r *http.Request)
+		expected int
+	}{
+		{"no credentials", http.MethodGet, "/public/style.css", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"basic auth", http.MethodGet, "/view", func(r *http.Request) { r.SetBasicAuth("akari", "secret") }, http.StatusOK},
+		{"wrong password", http.MethodGet, "/view", func(r *http.Request) { r.SetBasicAuth("akari", "wrong") }, http.StatusUnauthorized},
+		{"token", http.MethodPost, "/baseline", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		{"read-only token for api", http.MethodGet, "/api/runs", func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-only") }, http.StatusOK},
+		{"read-only token for html", http.MethodGet, "/view", func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-only") }, http.StatusForbidden},
+		{"read-only token for post", http.MethodPost, "/api/runs", func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-only") }, http.StatusForbidden},
+		{"wrong token", http.MethodGet, "/api/runs", func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, c.path, nil)
+			c.setup(r)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, c.expected, w.Code)
+		})
+	}
+}
+
+func TestWithAuthPasswordOnly(t *testing.T) {
+	config.Store(akari.AkariConfig{
+		Server: akari.ServerConfig{Auth: akari.ServerAuthConfig{Password: "secret"}},
+	})
+	t.Cleanup(func() { config.Store(akari.AkariConfig{}) })
+
+	// a password without a username fails closed
+	handler := withAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/view", nil)
+	r.SetBasicAuth("", "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLoadServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	load := func(content string) error {
+		path := filepath.Join(dir, "akari.toml")
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+		_, err := loadServerConfig(path)
+		return err
+	}
+
+	assert.NoError(t, load("[server.auth]\nusername = \"akari\"\npassword = \"secret\"\n"))
+	assert.Error(t, load("[server.auth]\npassword = \"secret\"\n"))
+	assert.Error(t, load("[server.auth\n"))
+
+	_, err := loadServerConfig(filepath.Join(dir, "missing.toml"))
+	assert.Error(t, err)
+
+	// the environment variables complete the config
+	t.Setenv("AKARI_AUTH_USERNAME", "akari")
+	assert.NoError(t, load("[server.auth]\npassword = \"secret\"\n"))
+}
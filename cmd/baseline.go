@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// baselinesFile keeps the pinned baseline of each log type in the log directory, so that it survives restarts
+const baselinesFile = "baselines.toml"
+
+// baselinesMutex serializes the read-modify-write of baselinesFile
+var baselinesMutex sync.Mutex
+
+// loadBaselines reads the pinned baseline file of each log type. Baselines which no longer exist are dropped.
+func loadBaselines(logDir string) (map[string]string, error) {
+	var stored map[string]string
+	if _, err := toml.DecodeFile(filepath.Join(logDir, baselinesFile), &stored); errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load %v: %w", baselinesFile, err)
+	}
+
+	baselines := map[string]string{}
+	for logType, rel := range stored {
+		path := filepath.Join(logDir, filepath.FromSlash(rel))
+		if err := checkInLogDir(logDir, path); err != nil {
+			slog.Debug("Skipped baseline", "type", logType, "path", path, "error", err)
+			continue
+		}
+
+		baselines[logType] = path
+	}
+
+	return baselines, nil
+}
+
+// readBaselines is loadBaselines which logs the error instead
+func readBaselines(logDir string) map[string]string {
+	baselines, err := loadBaselines(logDir)
+	if err != nil {
+		slog.Warn("Failed to load baselines", "error", err)
+		return map[string]string{}
+	}
+
+	return baselines
+}
+
+// pinBaseline pins the file as the baseline of the log type. An empty path unpins it.
+func pinBaseline(logDir string, logType string, path string) error {
+	baselinesMutex.Lock()
+	defer baselinesMutex.Unlock()
+
+	baselines, err := loadBaselines(logDir)
+	if err != nil {
+		return err
+	}
+
+	stored := map[string]string{}
+	for t, p := range baselines {
+		rel, err := filepath.Rel(logDir, p)
+		if err != nil {
+			return fmt.Errorf("failed to resolve baseline: %w", err)
+		}
+
+		stored[t] = filepath.ToSlash(rel)
+	}
+
+	if path == "" {
+		delete(stored, logType)
+	} else {
+		rel, err := filepath.Rel(logDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve baseline: %w", err)
+		}
+
+		stored[logType] = filepath.ToSlash(rel)
+	}
+
+	// write to a temporary file first so that readers never see a partial file
+	file, err := os.CreateTemp(logDir, ".baselines-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %v: %w", baselinesFile, err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := toml.NewEncoder(file).Encode(stored); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode %v: %w", baselinesFile, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %v: %w", baselinesFile, err)
+	}
+
+	if err := os.Rename(file.Name(), filepath.Join(logDir, baselinesFile)); err != nil {
+		return fmt.Errorf("failed to save %v: %w", baselinesFile, err)
+	}
+
+	return nil
+}
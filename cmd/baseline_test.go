@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myuon/akari/akari"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTestLogDir creates the runs 1 to n of nginx logs, the larger the newer, and loads the default config
+func setupTestLogDir(t *testing.T, n int) string {
+	c, err := loadConfig("../akari.init.toml")
+	assert.NoError(t, err)
+	config.Store(c)
+	t.Cleanup(func() { config.Store(akari.AkariConfig{}) })
+
+	logDir := t.TempDir()
+	for i := 1; i <= n; i++ {
+		path := filepath.Join(logDir, strconv.Itoa(i), "access.log")
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(testNginxLog), 0o644))
+
+		modifiedAt := time.Now().Add(time.Duration(i-n) * time.Minute)
+		assert.NoError(t, os.Chtimes(path, modifiedAt, modifiedAt))
+	}
+
+	return logDir
+}
+
+// serveTest calls the handler with the server data of the log directory
+func serveTest(handler http.HandlerFunc, logDir string, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, r.WithContext(context.WithValue(r.Context(), contextKey, ServerData{LogDir: logDir})))
+	return w
+}
+
+func testRunID(logDir string, run string) string {
+	return RunID(logDir, filepath.Join(logDir, run, "access.log"))
+}
+
+func TestBaselineHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 3)
+
+	pinType := func(logType string, file string) *httptest.ResponseRecorder {
+		form := url.Values{"type": {logType}, "file": {file}}
+		r := httptest.NewRequest(http.MethodPost, "/baseline", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serveTest(baselineHandler, logDir, r)
+	}
+	pin := func(file string) *httptest.ResponseRecorder {
+		return pinType("nginx", file)
+	}
+	prevRuns := func() map[string]string {
+		files, err := listLogFiles(logDir, logDir)
+		assert.NoError(t, err)
+
+		prev := map[string]string{}
+		for _, file := range files {
+			switch {
+			case file.IsBaseline:
+				prev[runName(logDir, file.DirPath)] = "baseline"
+			case file.PrevPath == "":
+				prev[runName(logDir, file.DirPath)] = ""
+			default:
+				prev[runName(logDir, file.DirPath)] = runName(logDir, filepath.Dir(file.PrevPath))
+			}
+		}
+		return prev
+	}
+
+	// each run is compared with the previous one by default
+	assert.Equal(t, map[string]string{"3": "2", "2": "1", "1": ""}, prevRuns())
+
+	w := pin(testRunID(logDir, "1"))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, map[string]string{"3": "1", "2": "1", "1": "baseline"}, prevRuns())
+
+	// the baseline is stored in the log directory, so that it survives restarts
+	baselines, err := loadBaselines(logDir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"nginx": filepath.Join(logDir, "1", "access.log")}, baselines)
+
+	w = pin("unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the type is checked against the config and the file, so that a wrong analyzer never parses the baseline
+	w = pinType("mysql", testRunID(logDir, "2"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	notesPath := filepath.Join(logDir, "2", "notes.txt")
+	assert.NoError(t, os.WriteFile(notesPath, []byte("not a log\n"), 0o644))
+	w = pin(RunID(logDir, notesPath))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, os.Remove(notesPath))
+
+	baselines, err = loadBaselines(logDir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"nginx": filepath.Join(logDir, "1", "access.log")}, baselines)
+
+	w = pin("")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, map[string]string{"3": "2", "2": "1", "1": ""}, prevRuns())
+
+	w = serveTest(baselineHandler, logDir, httptest.NewRequest(http.MethodGet, "/baseline", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestCompareHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 3)
+
+	compare := func(base string, target string) *httptest.ResponseRecorder {
+		query := url.Values{"base": {base}, "target": {target}}
+		return serveTest(compareHandler, logDir, httptest.NewRequest(http.MethodGet, "/compare?"+query.Encode(), nil))
+	}
+
+	w := compare(testRunID(logDir, "1"), testRunID(logDir, "3"))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/view", location.Path)
+	assert.Equal(t, "nginx", location.Query().Get("type"))
+	assert.Equal(t, testRunID(logDir, "3"), location.Query().Get("file"))
+	assert.Equal(t, testRunID(logDir, "1"), location.Query().Get("prev"))
+
+	w = compare("unknown", testRunID(logDir, "3"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = compare(testRunID(logDir, "1"), "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// runs of another log type cannot be compared
+	other := filepath.Join(logDir, "3", "mysql-slow.log")
+	assert.NoError(t, os.WriteFile(other, []byte("# Time: 2025-01-03T04:29:01.000000Z\n"), 0o644))
+	w = compare(testRunID(logDir, "1"), RunID(logDir, other))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
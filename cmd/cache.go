@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/myuon/akari/akari"
+)
+
+type fileStamp struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+func stampFile(path string) (fileStamp, error) {
+	if path == "" {
+		return fileStamp{}, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+
+	return fileStamp{Path: path, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+type analysisKey struct {
+	Log               fileStamp
+	Prev              fileStamp
+	ConfigFingerprint string
+}
+
+const analysisCacheSize = 64
+
+// tailKey identifies a log analyzed against the same previous file with the same config, whatever its content is
+type tailKey struct {
+	LogPath           string
+	PrevPath          string
+	ConfigFingerprint string
+}
+
+func (k analysisKey) tailKey() tailKey {
+	return tailKey{LogPath: k.Log.Path, PrevPath: k.Prev.Path, ConfigFingerprint: k.ConfigFingerprint}
+}
+
+const tailCacheSize = 8
+
+// parsedKey identifies the records parsed from a log, which the related logs page looks up for both logs
+type parsedKey struct {
+	Log               fileStamp
+	ConfigFingerprint string
+}
+
+// the parsed records are large, and the related logs page only needs a source and a target at once
+const parsedCacheSize = 4
+
+// analysisEntry is a summary of a log and its formatted result
+type analysisEntry struct {
+	Summary akari.SummaryRecords
+	Result  akari.TableData
+}
+
+// analysisCache keeps the formatted results in memory, so that flipping between runs does not even hash the files.
+// The snapshot store is the on-disk cache behind it.
+type analysisCache struct {
+	mutex   sync.Mutex
+	entries map[analysisKey]analysisEntry
+	// the last stamp of the logs analyzed, to find the growing logs
+	stamps map[tailKey]fileStamp
+	tails  map[tailKey]*tailState
+	parsed map[parsedKey]akari.LogRecords
+}
+
+func newAnalysisCache() *analysisCache {
+	return &analysisCache{
+		entries: map[analysisKey]analysisEntry{},
+		stamps:  map[tailKey]fileStamp{},
+		tails:   map[tailKey]*tailState{},
+		parsed:  map[parsedKey]akari.LogRecords{},
+	}
+}
+
+func (c *analysisCache) get(key analysisKey) (analysisEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *analysisCache) put(key analysisKey, entry analysisEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// evict an arbitrary entry, the cache only needs to hold the runs being browsed
+	if len(c.entries) >= analysisCacheSize {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+
+	c.entries[key] = entry
+	c.stamps[key.tailKey()] = key.Log
+}
+
+// tail returns the parsed records of the log if it has grown since it was analyzed last time.
+// Logs which never changed are left to the snapshot store.
+func (c *analysisCache) tail(key analysisKey) (*tailState, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	tailKey := key.tailKey()
+	if state, ok := c.tails[tailKey]; ok {
+		return state, true
+	}
+
+	if stamp, ok := c.stamps[tailKey]; !ok || stamp == key.Log {
+		return nil, false
+	}
+
+	if len(c.tails) >= tailCacheSize {
+		for k := range c.tails {
+			delete(c.tails, k)
+			break
+		}
+	}
+
+	state := &tailState{}
+	c.tails[tailKey] = state
+	return state, true
+}
+
+func (c *analysisCache) getParsed(key parsedKey) (akari.LogRecords, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	records, ok := c.parsed[key]
+	return records, ok
+}
+
+func (c *analysisCache) putParsed(key parsedKey, records akari.LogRecords) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.parsed) >= parsedCacheSize {
+		for k := range c.parsed {
+			delete(c.parsed, k)
+			break
+		}
+	}
+
+	c.parsed[key] = records
+}
+
+func (c *analysisCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	clear(c.entries)
+	clear(c.stamps)
+	clear(c.tails)
+	clear(c.parsed)
+}
+
+// tailState keeps the records parsed from a growing log, so that only the appended lines are parsed on each change
+type tailState struct {
+	mutex  sync.Mutex
+	file   os.FileInfo
+	offset int64
+	lines  int
+	parsed akari.LogRecords
+	// the bytes just before offset, to tell an appended log from a rewritten one
+	last []byte
+
+	prevStamp  fileStamp
+	prevParsed *akari.LogRecords
+}
+
+const tailCheckSize = 256
+
+// appended checks that the file still has the bytes parsed last time, so that only lines were appended
+func (s *tailState) appended(file *os.File) bool {
+	last := make([]byte, len(s.last))
+	if _, err := file.ReadAt(last, s.offset-int64(len(last))); err != nil {
+		return false
+	}
+
+	return bytes.Equal(last, s.last)
+}
+
+// summarize parses the lines appended since the last call, and summarizes all the records parsed so far
+func (s *tailState) summarize(analyzer akari.AnalyzerConfig, key analysisKey) (akari.SummaryRecords, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	parseOptions, err := analyzer.SummaryParseOptions()
+	if err != nil {
+		return akari.SummaryRecords{}, err
+	}
+
+	logFile, err := os.Open(key.Log.Path)
+	if err != nil {
+		return akari.SummaryRecords{}, err
+	}
+	defer logFile.Close()
+
+	info, err := logFile.Stat()
+	if err != nil {
+		return akari.SummaryRecords{}, err
+	}
+
+	// a rotated, truncated or rewritten log is parsed from the beginning
+	if s.file == nil || !os.SameFile(s.file, info) || info.Size() < s.offset || !s.appended(logFile) {
+		s.offset, s.lines, s.parsed, s.last = 0, 0, akari.LogRecords{}, nil
+	}
+	s.file = info
+
+	appended, err := io.ReadAll(io.NewSectionReader(logFile, s.offset, info.Size()-s.offset))
+	if err != nil {
+		return akari.SummaryRecords{}, fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	// the last line may be still being written
+	appended = appended[:bytes.LastIndexByte(appended, '\n')+1]
+
+	parseOptions.LineOffset = s.lines
+	parsed, err := akari.Parse(parseOptions, bytes.NewReader(appended), slog.Default())
+	if err != nil {
+		return akari.SummaryRecords{}, err
+	}
+
+	s.parsed.Append(parsed)
+	s.offset += int64(len(appended))
+	if len(appended) > 0 {
+		s.last = bytes.Clone(appended[max(0, len(appended)-tailCheckSize):])
+	}
+	s.lines += bytes.Count(appended, []byte("\n"))
+
+	if key.Prev.Path == "" {
+		s.prevParsed = nil
+	} else if s.prevParsed == nil || s.prevStamp != key.Prev {
+		prevFile, err := os.Open(key.Prev.Path)
+		if err != nil {
+			return akari.SummaryRecords{}, err
+		}
+		defer prevFile.Close()
+
+		prevParseOptions := parseOptions
+		prevParseOptions.KeepLines = false
+		prevParseOptions.LineOffset = 0
+
+		prevParsed, err := akari.Parse(prevParseOptions, prevFile, slog.Default())
+		if err != nil {
+			return akari.SummaryRecords{}, err
+		}
+
+		s.prevStamp = key.Prev
+		s.prevParsed = &prevParsed
+	}
+
+	slog.Debug("Parsed appended lines", "path", key.Log.Path, "bytes", len(appended))
+
+	// the column types are only known from the records, so nothing is compared until the first line is complete
+	if len(s.parsed.Records) == 0 {
+		return akari.SummarizeRecords(analyzer, s.parsed, nil, slog.Default())
+	}
+
+	return akari.SummarizeRecords(analyzer, s.parsed, s.prevParsed, slog.Default())
+}
+
+// tail is analysisCache.tail without the cache
+func (d ServerData) tail(key analysisKey) (*tailState, bool) {
+	if d.Cache == nil {
+		return nil, false
+	}
+
+	return d.Cache.tail(key)
+}
+
+// analyze returns the formatted result of the file, from the cache if neither the files nor the config changed
+func (d ServerData) analyze(analyzer akari.AnalyzerConfig, logPath string, prevPath string) (akari.TableData, error) {
+	entry, err := d.analysis(analyzer, logPath, prevPath)
+	return entry.Result, err
+}
+
+// summarize is analyze which returns the summary before formatting
+func (d ServerData) summarize(analyzer akari.AnalyzerConfig, logPath string, prevPath string) (akari.SummaryRecords, error) {
+	entry, err := d.analysis(analyzer, logPath, prevPath)
+	return entry.Summary, err
+}
+
+func (d ServerData) analysis(analyzer akari.AnalyzerConfig, logPath string, prevPath string) (analysisEntry, error) {
+	fingerprint, err := analyzer.Fingerprint()
+	if err != nil {
+		return analysisEntry{}, err
+	}
+
+	logStamp, err := stampFile(logPath)
+	if err != nil {
+		return analysisEntry{}, err
+	}
+
+	prevStamp, err := stampFile(prevPath)
+	if err != nil {
+		return analysisEntry{}, err
+	}
+
+	key := analysisKey{
+		Log:               logStamp,
+		Prev:              prevStamp,
+		ConfigFingerprint: fingerprint,
+	}
+	if d.Cache != nil {
+		if entry, ok := d.Cache.get(key); ok {
+			slog.Debug("Loaded cached result", "path", logPath)
+			return entry, nil
+		}
+	}
+
+	var summary akari.SummaryRecords
+	if state, ok := d.tail(key); ok {
+		summary, err = state.summarize(analyzer, key)
+	} else {
+		summary, err = d.summarizeFile(akari.SummarizeFileOptions{
+			Config:   analyzer,
+			LogPath:  logPath,
+			PrevPath: prevPath,
+			Logger:   slog.Default(),
+		})
+	}
+	if err != nil {
+		return analysisEntry{}, err
+	}
+
+	result, err := akari.FormatSummary(analyzer, summary, slog.Default())
+	if err != nil {
+		return analysisEntry{}, err
+	}
+
+	entry := analysisEntry{Summary: summary, Result: result}
+	if d.Cache != nil {
+		d.Cache.put(key, entry)
+	}
+
+	return entry, nil
+}
+
+// parse returns the records of the file, from the cache if neither the file nor the config changed.
+// The records are shared between requests and must not be modified.
+func (d ServerData) parse(analyzer akari.AnalyzerConfig, path string) (akari.LogRecords, error) {
+	if d.Cache == nil {
+		return parseFile(analyzer, path)
+	}
+
+	fingerprint, err := analyzer.Fingerprint()
+	if err != nil {
+		return akari.LogRecords{}, err
+	}
+
+	stamp, err := stampFile(path)
+	if err != nil {
+		return akari.LogRecords{}, err
+	}
+
+	key := parsedKey{Log: stamp, ConfigFingerprint: fingerprint}
+	if records, ok := d.Cache.getParsed(key); ok {
+		slog.Debug("Loaded cached records", "path", path)
+		return records, nil
+	}
+
+	records, err := parseFile(analyzer, path)
+	if err != nil {
+		return akari.LogRecords{}, err
+	}
+
+	d.Cache.putParsed(key, records)
+	return records, nil
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/myuon/akari/akari"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerDataAnalyzeCache(t *testing.T) {
+	var c akari.AkariConfig
+	_, err := toml.Decode(`
+[[analyzers]]
+name = "test"
+groupingKeys = ["Url"]
+sortKeys = ["Count"]
+
+[analyzers.parser]
+regexp = '''^(?P<Url>\S+) (?P<Time>\S+)$'''
+columns = [
+  { name = "Url" },
+  { name = "Time", converters = [{ type = "parseFloat64" }] },
+]
+
+[[analyzers.query]]
+from = "Time"
+columns = [{ name = "Count", function = "count" }]
+
+[[analyzers.query]]
+from = "Url"
+`, &c)
+	assert.NoError(t, err)
+
+	logPath := filepath.Join(t.TempDir(), "access.log")
+	assert.NoError(t, os.WriteFile(logPath, []byte("/a 1.0\n"), 0o644))
+
+	serverData := ServerData{Cache: newAnalysisCache()}
+
+	result, err := serverData.analyze(c.Analyzers[0], logPath, "")
+	assert.NoError(t, err)
+	assert.Len(t, result.Rows, 1)
+	assert.Len(t, serverData.Cache.entries, 1)
+
+	cached, err := serverData.analyze(c.Analyzers[0], logPath, "")
+	assert.NoError(t, err)
+	assert.Equal(t, result, cached)
+
+	// a changed file is analyzed again
+	assert.NoError(t, os.WriteFile(logPath, []byte("/a 1.0\n/b 1.0\n"), 0o644))
+	assert.NoError(t, os.Chtimes(logPath, time.Now(), time.Now().Add(time.Second)))
+
+	result, err = serverData.analyze(c.Analyzers[0], logPath, "")
+	assert.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+
+	serverData.Cache.clear()
+	assert.Empty(t, serverData.Cache.entries)
+}
+
+func TestServerDataAnalyzeGrowingLog(t *testing.T) {
+	logDir := setupTestLogDir(t, 2)
+	analyzer, ok := findAnalyzer(config.Load(), "nginx")
+	assert.True(t, ok)
+
+	logPath := filepath.Join(logDir, "2", "access.log")
+	prevPath := filepath.Join(logDir, "1", "access.log")
+	serverData := ServerData{Cache: newAnalysisCache()}
+
+	analyzeAt := func(modifiedAt time.Time) akari.TableData {
+		assert.NoError(t, os.Chtimes(logPath, modifiedAt, modifiedAt))
+		result, err := serverData.analyze(analyzer, logPath, prevPath)
+		assert.NoError(t, err)
+		return result
+	}
+	expected := func() akari.TableData {
+		result, err := ServerData{}.analyze(analyzer, logPath, prevPath)
+		assert.NoError(t, err)
+		return result
+	}
+
+	analyzeAt(time.Now())
+	assert.Empty(t, serverData.Cache.tails)
+
+	// the appended lines are parsed on top of the records parsed before
+	appendLog := func(content string) {
+		file, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0o644)
+		assert.NoError(t, err)
+		_, err = file.WriteString(content)
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+	}
+	appendLog(strings.ReplaceAll(testNginxLog, "/api/b", "/api/c"))
+	assert.Equal(t, expected(), analyzeAt(time.Now().Add(time.Second)))
+	assert.Len(t, serverData.Cache.tails, 1)
+
+	appendLog(testNginxLog)
+	assert.Equal(t, expected(), analyzeAt(time.Now().Add(2*time.Second)))
+
+	// a rewritten log is parsed again
+	assert.NoError(t, os.WriteFile(logPath, []byte(strings.ReplaceAll(testNginxLog, "/api/a", "/api/d")+testNginxLog), 0o644))
+	assert.Equal(t, expected(), analyzeAt(time.Now().Add(3*time.Second)))
+}
+
+func TestServerDataAnalyzePartialLine(t *testing.T) {
+	logDir := setupTestLogDir(t, 2)
+	analyzer, ok := findAnalyzer(config.Load(), "nginx")
+	assert.True(t, ok)
+
+	logPath := filepath.Join(logDir, "2", "access.log")
+	prevPath := filepath.Join(logDir, "1", "access.log")
+	serverData := ServerData{Cache: newAnalysisCache()}
+
+	_, err := serverData.analyze(analyzer, logPath, prevPath)
+	assert.NoError(t, err)
+
+	// the log is rewritten, and its first line is still being written
+	line, _, _ := strings.Cut(testNginxLog, "\n")
+	assert.NoError(t, os.WriteFile(logPath, []byte(line[:len(line)/2]), 0o644))
+	assert.NoError(t, os.Chtimes(logPath, time.Now().Add(time.Second), time.Now().Add(time.Second)))
+
+	result, err := serverData.analyze(analyzer, logPath, prevPath)
+	assert.NoError(t, err)
+	assert.Len(t, serverData.Cache.tails, 1)
+	assert.Empty(t, result.Rows)
+
+	// the line is parsed once it is complete
+	assert.NoError(t, os.WriteFile(logPath, []byte(line+"\n"), 0o644))
+	assert.NoError(t, os.Chtimes(logPath, time.Now().Add(2*time.Second), time.Now().Add(2*time.Second)))
+
+	result, err = serverData.analyze(analyzer, logPath, prevPath)
+	assert.NoError(t, err)
+	assert.Len(t, result.Rows, 1)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/myuon/akari/akari"
+)
+
+// runs needed to compute a meaningful correlation
+const correlationMinRuns = 3
+
+type CorrelationRow struct {
+	Rank        int
+	Key         string
+	Group       []akari.GroupValue
+	Correlation string
+	Runs        int
+}
+
+// correlationViewHandler ranks the groups by the correlation between the column and the benchmark score across runs
+func correlationViewHandler(w http.ResponseWriter, r *http.Request) {
+	logType := r.URL.Query().Get("type")
+	if logType == "" {
+		logType = "nginx"
+	}
+
+	column := r.URL.Query().Get("column")
+	if column == "" {
+		column = "Total"
+	}
+
+	serverData := UseServerData(r)
+
+	analyzer, ok := findAnalyzer(config.Load(), logType)
+	if !ok {
+		http.Error(w, "Unknown log type", http.StatusBadRequest)
+		return
+	}
+
+	runs, err := serverData.summarizeRuns(analyzer)
+	if err != nil {
+		http.Error(w, "Failed to analyze logs", http.StatusInternalServerError)
+		slog.Error("Failed to analyze logs", "error", err)
+		return
+	}
+
+	scored := 0
+	columns := []string{}
+	for _, run := range runs {
+		if run.Score != nil {
+			scored++
+		}
+
+		if len(columns) == 0 {
+			for _, c := range run.Summary.Columns {
+				if c.Type.IsNumeric() {
+					columns = append(columns, c.Name)
+				}
+			}
+		}
+	}
+
+	if len(runs) > 0 && !slices.Contains(columns, column) {
+		http.Error(w, "Column must be numeric", http.StatusBadRequest)
+		return
+	}
+
+	correlations, err := akari.CorrelateScore(runs, column, correlationMinRuns)
+	if err != nil {
+		http.Error(w, "Unknown column", http.StatusBadRequest)
+		return
+	}
+
+	rows := []CorrelationRow{}
+	groupingKeys := []string{}
+	if len(runs) > 0 {
+		groupingKeys = runs[0].Summary.GroupingKeys
+	}
+	for i, correlation := range correlations {
+		rows = append(rows, CorrelationRow{
+			Rank:        i + 1,
+			Key:         correlation.Key,
+			Group:       akari.GroupValues(groupingKeys, correlation.Group),
+			Correlation: fmt.Sprintf("%+.3f", correlation.Correlation),
+			Runs:        correlation.Runs,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := serverData.TemplateFiles.ExecuteTemplate(w, "correlation.html", map[string]any{
+		"LogType":      logType,
+		"Column":       column,
+		"Columns":      columns,
+		"Runs":         len(runs),
+		"ScoredRuns":   scored,
+		"MinRuns":      correlationMinRuns,
+		"GroupingKeys": groupingKeys,
+		"Rows":         rows,
+	}); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Println("Template execution error:", err)
+		return
+	}
+}
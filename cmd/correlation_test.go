@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelationViewHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 3)
+	// the response time of /api/a grows with the score
+	for i := 1; i <= 3; i++ {
+		dir := filepath.Join(logDir, strconv.Itoa(i))
+		log := strings.ReplaceAll(testNginxLog, "0.011", fmt.Sprintf("%d.000", i))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "access.log"), []byte(log), 0o644))
+
+		score := float64(i * 100)
+		assert.NoError(t, saveRunMeta(dir, RunMeta{Score: &score}))
+	}
+
+	serverData := ServerData{
+		LogDir:        logDir,
+		TemplateFiles: template.Must(template.ParseFS(os.DirFS(".."), "templates/*.html")),
+		Cache:         newAnalysisCache(),
+	}
+	correlation := func(column string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/correlation?"+url.Values{"type": {"nginx"}, "column": {column}}.Encode(), nil)
+		w := httptest.NewRecorder()
+		correlationViewHandler(w, r.WithContext(context.WithValue(r.Context(), contextKey, serverData)))
+		return w
+	}
+
+	w := correlation("Total")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "3 of 3 runs have a score")
+	assert.Contains(t, w.Body.String(), "<code>/api/a</code>")
+	assert.Contains(t, w.Body.String(), "&#43;1.000")
+	// the runs are summarized once, and the other requests reuse them
+	assert.Len(t, serverData.Cache.entries, 3)
+
+	w = correlation("Mean")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, serverData.Cache.entries, 3)
+
+	w = correlation("Method")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = correlation("Unknown")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
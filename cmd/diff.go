@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/myuon/akari/akari"
+)
+
+type DiffOptions struct {
+	ConfigFile  string
+	PrevFile    string
+	LogFile     string
+	Color       bool
+	FailOn      []string
+	Writer      io.Writer
+	ErrorWriter io.Writer
+}
+
+func Diff(options DiffOptions) error {
+	logger := akari.NewDurationLogger(slog.Default())
+
+	config, err := loadConfig(options.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	logFile, err := os.Open(options.LogFile)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	line, err := peekLine(logFile)
+	if err != nil {
+		return err
+	}
+
+	analyzer, ok := detectAnalyzer(config, line)
+	if !ok {
+		return fmt.Errorf("no analyzer matched the log file: %v", options.LogFile)
+	}
+
+	logger.Debug("Matched analyzer", "analyzer", analyzer.Name)
+
+	result, err := analyzeFiles(analyzer, logFile, options.PrevFile, logger)
+	if err != nil {
+		return err
+	}
+
+	result.WriteText(options.Writer, akari.TextOptions{
+		ShowRank:       analyzer.ShowRank,
+		DiffHeaders:    analyzer.Diffs,
+		HigherIsBetter: analyzer.HigherIsBetter,
+		Color:          options.Color,
+		ShowStatus:     true,
+	})
+
+	if len(result.Gone) > 0 {
+		fmt.Fprintf(options.Writer, "\nGone groups (%d):\n", len(result.Gone))
+		result.GoneTable().WriteText(options.Writer, akari.TextOptions{})
+	}
+
+	logger.Debug("Printed table")
+
+	return checkAssertions(analyzer, result, options.FailOn, options.ErrorWriter)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "access.log")
+	prevFile := filepath.Join(dir, "prev.log")
+	assert.NoError(t, os.WriteFile(logFile, []byte(strings.ReplaceAll(testNginxLog, "0.110", "2.000")), 0o644))
+	assert.NoError(t, os.WriteFile(prevFile, []byte(strings.ReplaceAll(testNginxLog, "0.110", "1.000")), 0o644))
+
+	output := bytes.NewBuffer(nil)
+	assert.NoError(t, Diff(DiffOptions{
+		ConfigFile:  "../akari.init.toml",
+		LogFile:     logFile,
+		PrevFile:    prevFile,
+		Color:       true,
+		Writer:      output,
+		ErrorWriter: output,
+	}))
+
+	assert.Contains(t, output.String(), "/api/a")
+	// Total of /api/b doubled from the previous file, which is a regression
+	assert.Contains(t, output.String(), "\x1b[31m(+100%)\x1b[0m")
+}
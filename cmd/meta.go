@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	metaTomlFile = "meta.toml"
+	metaJsonFile = "meta.json"
+)
+
+// RunMeta describes a run. It is read from meta.toml or meta.json in the run directory.
+type RunMeta struct {
+	Commit string   `toml:"commit,omitempty" json:"commit,omitempty"`
+	Branch string   `toml:"branch,omitempty" json:"branch,omitempty"`
+	Score  *float64 `toml:"score,omitempty" json:"score,omitempty"`
+	Notes  string   `toml:"notes,omitempty" json:"notes,omitempty"`
+}
+
+func (m RunMeta) IsEmpty() bool {
+	return m.Commit == "" && m.Branch == "" && m.Score == nil && m.Notes == ""
+}
+
+func (m RunMeta) ScoreString() string {
+	if m.Score == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*m.Score, 'f', -1, 64)
+}
+
+// isMetaFile reports the sidecar files of akari and the uploads being written, which are not logs
+func isMetaFile(name string) bool {
+	return name == metaTomlFile || name == metaJsonFile || name == baselinesFile || isPartialUpload(name)
+}
+
+func loadRunMeta(dir string) (RunMeta, error) {
+	var meta RunMeta
+	if _, err := toml.DecodeFile(filepath.Join(dir, metaTomlFile), &meta); err == nil {
+		return meta, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return RunMeta{}, fmt.Errorf("failed to load %v: %w", metaTomlFile, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, metaJsonFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return RunMeta{}, nil
+	} else if err != nil {
+		return RunMeta{}, fmt.Errorf("failed to load %v: %w", metaJsonFile, err)
+	}
+
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return RunMeta{}, fmt.Errorf("failed to load %v: %w", metaJsonFile, err)
+	}
+
+	return meta, nil
+}
+
+// saveRunMeta writes meta.json if the run already has one, otherwise meta.toml
+func saveRunMeta(dir string, meta RunMeta) error {
+	if _, err := os.Stat(filepath.Join(dir, metaJsonFile)); err == nil {
+		data, err := json.MarshalIndent(meta, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %v: %w", metaJsonFile, err)
+		}
+
+		return os.WriteFile(filepath.Join(dir, metaJsonFile), data, 0o644)
+	}
+
+	file, err := os.Create(filepath.Join(dir, metaTomlFile))
+	if err != nil {
+		return fmt.Errorf("failed to create %v: %w", metaTomlFile, err)
+	}
+	defer file.Close()
+
+	if err := toml.NewEncoder(file).Encode(meta); err != nil {
+		return fmt.Errorf("failed to encode %v: %w", metaTomlFile, err)
+	}
+
+	return nil
+}
+
+// readRunMeta is loadRunMeta which logs the error instead, so that a broken sidecar file does not break the pages
+func readRunMeta(dir string) RunMeta {
+	meta, err := loadRunMeta(dir)
+	if err != nil {
+		slog.Warn("Failed to load run metadata", "dir", dir, "error", err)
+	}
+
+	return meta
+}
+
+func metaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	run := r.FormValue("run")
+	if run == "" {
+		http.Error(w, "Run not specified", http.StatusBadRequest)
+		return
+	}
+
+	logDir := UseServerData(r).LogDir
+	dir, err := resolveDir(logDir, run)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+
+	// the metadata describes a run directory, and the log directory itself is not a run
+	if filepath.Clean(dir) == filepath.Clean(logDir) {
+		http.Error(w, "Metadata can only be set on a run directory", http.StatusBadRequest)
+		return
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	meta := RunMeta{
+		Commit: r.FormValue("commit"),
+		Branch: r.FormValue("branch"),
+		Notes:  r.FormValue("notes"),
+	}
+	if score := r.FormValue("score"); score != "" {
+		value, err := strconv.ParseFloat(score, 64)
+		if err != nil {
+			http.Error(w, "Score must be a number", http.StatusBadRequest)
+			return
+		}
+
+		meta.Score = &value
+	}
+
+	if err := saveRunMeta(dir, meta); err != nil {
+		http.Error(w, "Failed to save metadata", http.StatusInternalServerError)
+		slog.Error("Failed to save metadata", "error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunMeta(t *testing.T) {
+	dir := t.TempDir()
+
+	meta, err := loadRunMeta(dir)
+	assert.NoError(t, err)
+	assert.True(t, meta.IsEmpty())
+
+	score := 12345.5
+	assert.NoError(t, saveRunMeta(dir, RunMeta{Commit: "abc123", Branch: "main", Score: &score}))
+	assert.FileExists(t, filepath.Join(dir, metaTomlFile))
+
+	meta, err = loadRunMeta(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, RunMeta{Commit: "abc123", Branch: "main", Score: &score}, meta)
+	assert.Equal(t, "12345.5", meta.ScoreString())
+
+	jsonDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(jsonDir, metaJsonFile), []byte(`{"commit": "def456", "score": 100}`), 0o644))
+
+	meta, err = loadRunMeta(jsonDir)
+	assert.NoError(t, err)
+	assert.Equal(t, "def456", meta.Commit)
+	assert.Equal(t, "100", meta.ScoreString())
+
+	// meta.json is kept as json
+	assert.NoError(t, saveRunMeta(jsonDir, RunMeta{Notes: "add index"}))
+	assert.NoFileExists(t, filepath.Join(jsonDir, metaTomlFile))
+
+	meta, err = loadRunMeta(jsonDir)
+	assert.NoError(t, err)
+	assert.Equal(t, RunMeta{Notes: "add index"}, meta)
+
+	// the keys are the same as meta.toml
+	content, err := os.ReadFile(filepath.Join(jsonDir, metaJsonFile))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"notes": "add index"}`, string(content))
+}
+
+func TestMetaHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 1)
+
+	post := func(run string) *httptest.ResponseRecorder {
+		form := url.Values{"run": {run}, "commit": {"abc123"}, "score": {"100"}}
+		r := httptest.NewRequest(http.MethodPost, "/meta", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serveTest(metaHandler, logDir, r)
+	}
+
+	w := post("1")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+
+	meta, err := loadRunMeta(filepath.Join(logDir, "1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", meta.Commit)
+	assert.Equal(t, "100", meta.ScoreString())
+
+	// the log directory itself is not a run
+	w = post(".")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = post("1/..")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoFileExists(t, filepath.Join(logDir, metaTomlFile))
+
+	w = post("../")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = post("2")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
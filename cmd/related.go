@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/myuon/akari/akari"
+)
+
+// nginx logs the timestamps in seconds
+const relatedMargin = time.Second
+
+const relatedLimit = 100
+
+type RelatedRow struct {
+	Rank  int
+	Key   string
+	Group []akari.GroupValue
+	Count int
+	Total string
+}
+
+func parseFile(analyzer akari.AnalyzerConfig, path string) (akari.LogRecords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return akari.LogRecords{}, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	parseOptions, err := analyzer.ParseOptions()
+	if err != nil {
+		return akari.LogRecords{}, fmt.Errorf("failed to get parse options: %w", err)
+	}
+
+	return akari.Parse(parseOptions, file, slog.Default())
+}
+
+// relatedViewHandler lists the groups of another log in the same run, which ran while the requests of the group were being served
+func relatedViewHandler(w http.ResponseWriter, r *http.Request) {
+	serverData := UseServerData(r)
+
+	sourceID := r.URL.Query().Get("file")
+	sourcePath, err := resolveRun(serverData.LogDir, sourceID)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Key not specified", http.StatusBadRequest)
+		return
+	}
+
+	sourceAnalyzer, ok := resolveAnalyzer(r.URL.Query().Get("type"), sourcePath)
+	if !ok {
+		http.Error(w, "Unknown log type", http.StatusBadRequest)
+		return
+	}
+
+	// the other logs of the run
+	files, err := listLogFiles(serverData.LogDir, filepath.Dir(sourcePath))
+	if err != nil {
+		http.Error(w, "Failed to list files", http.StatusInternalServerError)
+		slog.Error("Failed to list files", "error", err)
+		return
+	}
+	targets := slices.DeleteFunc(files, func(file FileData) bool {
+		return file.Path == sourcePath || file.DirPath != filepath.Dir(sourcePath) || file.LogType == "unknown"
+	})
+
+	data := map[string]any{
+		"Title":    runName(serverData.LogDir, sourcePath),
+		"LogType":  sourceAnalyzer.Name,
+		"FileID":   sourceID,
+		"Key":      key,
+		"Targets":  targets,
+		"Join":     r.URL.Query().Get("join"),
+		"Duration": sourceAnalyzer.LatencyColumn(),
+		"Margin":   relatedMargin,
+	}
+
+	durationUnit, err := sourceAnalyzer.LatencyDuration()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data["DurationUnit"] = durationUnit
+
+	source, err := serverData.parse(sourceAnalyzer, sourcePath)
+	if err != nil {
+		http.Error(w, "Failed to analyze log", http.StatusInternalServerError)
+		slog.Error("Failed to parse log", "path", sourcePath, "error", err)
+		return
+	}
+	if _, ok := source.Records[key]; !ok {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	data["Group"] = akari.GroupValues(source.GroupingKeys, source.Groups[key])
+	data["Requests"] = len(source.Records[key])
+
+	var target FileData
+	if len(targets) > 0 {
+		target = targets[0]
+	}
+	if targetID := r.URL.Query().Get("target"); targetID != "" {
+		index := slices.IndexFunc(targets, func(file FileData) bool {
+			return file.ID == targetID
+		})
+		if index < 0 {
+			http.Error(w, "Target not found in the run", http.StatusNotFound)
+			return
+		}
+
+		target = targets[index]
+	}
+	data["Target"] = target
+
+	if target.Path != "" {
+		targetAnalyzer, ok := findAnalyzer(config.Load(), target.LogType)
+		if !ok {
+			http.Error(w, "Unknown log type", http.StatusBadRequest)
+			return
+		}
+
+		targetRecords, err := serverData.parse(targetAnalyzer, target.Path)
+		if err != nil {
+			http.Error(w, "Failed to analyze log", http.StatusInternalServerError)
+			slog.Error("Failed to parse log", "path", target.Path, "error", err)
+			return
+		}
+
+		// the columns which both logs have can be joined
+		joinColumns := []string{}
+		for _, column := range source.Columns {
+			if column.Name != "Timestamp" && targetRecords.Columns.GetIndex(column.Name) >= 0 {
+				joinColumns = append(joinColumns, column.Name)
+			}
+		}
+		data["JoinColumns"] = joinColumns
+
+		groups, err := akari.CorrelateLogs(akari.CorrelateLogsOptions{
+			Source:         source,
+			Key:            key,
+			DurationColumn: sourceAnalyzer.LatencyColumn(),
+			DurationUnit:   durationUnit,
+			Target:         targetRecords,
+			ValueColumn:    targetAnalyzer.LatencyColumn(),
+			JoinColumn:     r.URL.Query().Get("join"),
+			Margin:         relatedMargin,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rows := []RelatedRow{}
+		for i, group := range groups[:min(len(groups), relatedLimit)] {
+			rows = append(rows, RelatedRow{
+				Rank:  i + 1,
+				Key:   group.Key,
+				Group: akari.GroupValues(targetRecords.GroupingKeys, group.Group),
+				Count: group.Count,
+				Total: fmt.Sprintf("%.3f", group.Total),
+			})
+		}
+		data["Rows"] = rows
+		data["Groups"] = len(groups)
+		data["ValueColumn"] = cmp.Or(targetAnalyzer.LatencyColumn(), "-")
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := serverData.TemplateFiles.ExecuteTemplate(w, "related.html", data); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Println("Template execution error:", err)
+		return
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelatedViewHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 1)
+	backendPath := filepath.Join(logDir, "1", "backend.log")
+	assert.NoError(t, os.WriteFile(backendPath, []byte(strings.ReplaceAll(testNginxLog, "/api/", "/backend/")), 0o644))
+
+	analyzer, ok := findAnalyzer(config.Load(), "nginx")
+	assert.True(t, ok)
+	source, err := parseFile(analyzer, filepath.Join(logDir, "1", "access.log"))
+	assert.NoError(t, err)
+
+	key := ""
+	for k, group := range source.Groups {
+		if group[1] == "/api/a" {
+			key = k
+		}
+	}
+	assert.NotEmpty(t, key)
+
+	serverData := ServerData{
+		LogDir:        logDir,
+		TemplateFiles: template.Must(template.ParseFS(os.DirFS(".."), "templates/*.html")),
+		Cache:         newAnalysisCache(),
+	}
+	related := func(query url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/related?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		relatedViewHandler(w, r.WithContext(context.WithValue(r.Context(), contextKey, serverData)))
+		return w
+	}
+	file := testRunID(logDir, "1")
+	backend := RunID(logDir, backendPath)
+
+	// the other log of the run is the default target, and both of its requests ran in the window
+	w := related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<option value="`+backend+`" selected>`)
+	assert.Contains(t, w.Body.String(), "/backend/a")
+	assert.Contains(t, w.Body.String(), "/backend/b")
+	assert.Contains(t, w.Body.String(), `<option value="Url"`)
+	// both logs are parsed once, and the other requests reuse them
+	assert.Len(t, serverData.Cache.parsed, 2)
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}, "target": {backend}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/backend/a")
+
+	// the source log itself is not a target
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}, "target": {file}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// no record of the backend log has the same Url
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}, "join": {"Url"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "/backend/a")
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}, "join": {"Method"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/backend/a")
+	assert.NotContains(t, w.Body.String(), "/backend/b")
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}, "join": {"Unknown"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {"unknown"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// unrecognized files in the run are not offered as targets
+	notesPath := filepath.Join(logDir, "1", "notes.txt")
+	assert.NoError(t, os.WriteFile(notesPath, []byte("not a log\n"), 0o644))
+	notes := RunID(logDir, notesPath)
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), notes)
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}, "target": {notes}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// even when they are the only other files of the run
+	assert.NoError(t, os.Remove(backendPath))
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), notes)
+
+	// an invalid latency unit is not read as seconds
+	c := config.Load()
+	c.Analyzers[0].LatencyUnit = "seconds"
+	config.Store(c)
+
+	w = related(url.Values{"type": {"nginx"}, "file": {file}, "key": {key}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/myuon/akari/akari"
+)
+
+type ReportOptions struct {
+	ConfigFile    string
+	LogFile       string
+	PrevFile      string
+	OutputFile    string
+	TemplateFiles *template.Template
+	PublicFS      fs.FS
+}
+
+func Report(options ReportOptions) error {
+	logger := akari.NewDurationLogger(slog.Default())
+
+	config, err := loadConfig(options.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	logFile, err := os.Open(options.LogFile)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	line, err := peekLine(logFile)
+	if err != nil {
+		return err
+	}
+
+	analyzer, ok := detectAnalyzer(config, line)
+	if !ok {
+		return fmt.Errorf("no analyzer matched the log file: %v", options.LogFile)
+	}
+
+	logger.Debug("Matched analyzer", "analyzer", analyzer.Name)
+
+	result, err := analyzeFiles(analyzer, logFile, options.PrevFile, logger)
+	if err != nil {
+		return err
+	}
+
+	style, err := fs.ReadFile(options.PublicFS, "public/style.css")
+	if err != nil {
+		return fmt.Errorf("failed to read style: %w", err)
+	}
+
+	script, err := fs.ReadFile(options.PublicFS, "public/table.js")
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+
+	output, err := os.Create(options.OutputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer output.Close()
+
+	if err := options.TemplateFiles.ExecuteTemplate(output, "report.html", map[string]any{
+		"Title":       options.LogFile,
+		"PrevPath":    options.PrevFile,
+		"LogType":     analyzer.Name,
+		"GeneratedAt": time.Now().Format(time.DateTime),
+		"TableData": result.Html(akari.HtmlOptions{
+			ShowRank:    analyzer.ShowRank,
+			DiffHeaders: analyzer.Diffs,
+		}),
+		"GoneTableData": result.GoneTable().Html(akari.HtmlOptions{}),
+		"Style":         template.CSS(style),
+		"Script":        template.JS(script),
+		"toStyle":       akari.HtmlStyle,
+		"toAttrs":       akari.HtmlAttrs,
+	}); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+
+	logger.Debug("Wrote report", "path", options.OutputFile)
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testNginxLog = `1.2.3.4 - - [03/Jan/2025:13:29:01 +0900] "GET /api/a HTTP/1.1" 200 121 "-" "curl" 0.011
+1.2.3.4 - - [03/Jan/2025:13:29:01 +0900] "POST /api/b HTTP/1.1" 200 11 "-" "curl" 0.110
+`
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "access.log")
+	prevFile := filepath.Join(dir, "prev.log")
+	output := filepath.Join(dir, "report.html")
+	assert.NoError(t, os.WriteFile(logFile, []byte(testNginxLog), 0o644))
+	assert.NoError(t, os.WriteFile(prevFile, []byte(strings.ReplaceAll(testNginxLog, "0.110", "0.220")), 0o644))
+
+	assert.NoError(t, Report(ReportOptions{
+		ConfigFile:    "../akari.init.toml",
+		LogFile:       logFile,
+		PrevFile:      prevFile,
+		OutputFile:    output,
+		TemplateFiles: template.Must(template.ParseFS(os.DirFS(".."), "templates/*.html")),
+		PublicFS:      os.DirFS(".."),
+	}))
+
+	html, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), "/api/a")
+	assert.Contains(t, string(html), "/api/b")
+	assert.Contains(t, string(html), `data-diff="true"`)
+	// Total of /api/b halved from the previous file
+	assert.Contains(t, string(html), "(-50%)")
+}
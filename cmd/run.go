@@ -5,17 +5,19 @@ import (
 	"io"
 	"log/slog"
 	"os"
-	"strings"
 
-	"github.com/BurntSushi/toml"
 	"github.com/myuon/akari/akari"
 )
 
 type RunOptions struct {
 	ConfigFile string
 	LogFile    string
-	GlobalSeed uint64
-	Writer     io.Writer
+	Format     akari.OutputFormat
+	FailOn     []string
+	// overrides the outliers count of the analyzer if positive
+	Outliers    int
+	Writer      io.Writer
+	ErrorWriter io.Writer
 }
 
 func Run(options RunOptions) error {
@@ -26,64 +28,64 @@ func Run(options RunOptions) error {
 
 	logger.Debug("Loading config", "path", configFilePath)
 
-	var config akari.AkariConfig
-	if _, err := toml.DecodeFile(configFilePath, &config); err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	config, err := loadConfig(configFilePath)
+	if err != nil {
+		return err
 	}
 
 	logFile, err := os.Open(logFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
 	}
+	defer logFile.Close()
 
 	logger.Debug("Loaded config", "config", config)
 
-	line := make([]byte, 512)
-	n, err := logFile.Read(line)
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("failed to read log file: %w", err)
-	}
-	line = line[:n]
-
-	if strings.Contains(string(line), "\n") {
-		line = []byte(strings.SplitN(string(line), "\n", 2)[0])
-	}
-
-	if _, err := logFile.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to rewind log file: %w", err)
+	line, err := peekLine(logFile)
+	if err != nil {
+		return err
 	}
 
 	logger.Debug("Read first line", "line", string(line))
 
 	tableData := akari.TableData{}
-	for _, analyzer := range config.Analyzers {
-		if analyzer.Parser.RegExp.Match(line) {
-			logger.Debug("Matched analyzer", "analyzer", analyzer.Name)
-
-			result, err := akari.Analyze(akari.AnalyzeOptions{
-				Config:  analyzer,
-				Source:  logFile,
-				HasPrev: false,
-				Prev:    nil,
-				Logger:  logger,
-				Seed:    options.GlobalSeed,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to analyze: %w", err)
+	analyzer, matched := detectAnalyzer(config, line)
+	if matched {
+		logger.Debug("Matched analyzer", "analyzer", analyzer.Name)
+
+		if options.Outliers > 0 {
+			outliers := akari.OutliersConfig{Count: options.Outliers}
+			if analyzer.Outliers != nil {
+				outliers.Column = analyzer.Outliers.Column
 			}
+			analyzer.Outliers = &outliers
+		}
 
-			tableData = result
-			break
+		result, err := akari.Analyze(akari.AnalyzeOptions{
+			Config:  analyzer,
+			Source:  logFile,
+			HasPrev: false,
+			Prev:    nil,
+			Logger:  logger,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to analyze: %w", err)
 		}
 
-		logger.Debug("Skipped analyzer", "analyzer", analyzer.Name)
+		tableData = result
 	}
 
 	logger.Debug("Analyzed log")
 
-	tableData.Write(options.Writer)
+	if err := tableData.WriteAs(options.Writer, options.Format); err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
 
 	logger.Debug("Printed table")
 
+	if matched {
+		return checkAssertions(analyzer, tableData, options.FailOn, options.ErrorWriter)
+	}
+
 	return nil
 }
@@ -13,7 +13,6 @@ func TestAnalyze(t *testing.T) {
 	if err := Run(RunOptions{
 		ConfigFile: "../akari.init.toml",
 		LogFile:    "../testdata/nginx-isucon14.log",
-		GlobalSeed: 0,
 		Writer:     writer,
 	}); err != nil {
 		t.Fatalf("failed to analyze: %v", err)
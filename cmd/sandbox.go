@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	errRunNotFound    = errors.New("run not found")
+	errOutsideLogDir  = errors.New("file is outside of the log directory")
+	errRunNotSelected = errors.New("run not specified")
+)
+
+// RunID is an opaque identifier of a file in the log directory, so that clients never pass file paths
+func RunID(logDir string, path string) string {
+	rel, err := filepath.Rel(logDir, path)
+	if err != nil {
+		rel = path
+	}
+
+	hash := sha256.Sum256([]byte(filepath.ToSlash(rel)))
+	return hex.EncodeToString(hash[:8])
+}
+
+// resolveInLogDir resolves the symlinks of the path, and returns it in the log directory.
+// Opening the returned path opens the file which was checked, even if a symlink is swapped afterwards.
+func resolveInLogDir(logDir string, path string) (string, error) {
+	root, err := filepath.EvalSymlinks(logDir)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", errRunNotFound
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errOutsideLogDir
+	}
+
+	return filepath.Join(logDir, rel), nil
+}
+
+// checkInLogDir checks that the path stays in the log directory after resolving symlinks
+func checkInLogDir(logDir string, path string) error {
+	_, err := resolveInLogDir(logDir, path)
+	return err
+}
+
+// runIndex caches the paths of the run IDs in the log directory, so that requests do not walk the whole directory.
+// It is reset by the watcher and on config reload.
+type runIndex struct {
+	mutex  sync.Mutex
+	logDir string
+	paths  map[string]string
+}
+
+var runs = &runIndex{}
+
+func (i *runIndex) build(logDir string) error {
+	paths := map[string]string{}
+	if err := filepath.WalkDir(logDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || isMetaFile(entry.Name()) {
+			return nil
+		}
+
+		paths[RunID(logDir, path)] = path
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	i.logDir = logDir
+	i.paths = paths
+	return nil
+}
+
+// lookup finds the path of the run ID. The index is rebuilt once on a miss, since files may be added before the watcher notices.
+func (i *runIndex) lookup(logDir string, id string) (string, bool, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	built := false
+	if i.paths == nil || i.logDir != logDir {
+		if err := i.build(logDir); err != nil {
+			return "", false, err
+		}
+		built = true
+	}
+
+	if path, ok := i.paths[id]; ok {
+		return path, true, nil
+	}
+	if built {
+		return "", false, nil
+	}
+
+	if err := i.build(logDir); err != nil {
+		return "", false, err
+	}
+
+	path, ok := i.paths[id]
+	return path, ok, nil
+}
+
+func (i *runIndex) reset() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.paths = nil
+}
+
+// resolveRun finds the file of the run ID in the log directory.
+// The returned path has the symlinks resolved, so that it is the file which was checked.
+func resolveRun(logDir string, id string) (string, error) {
+	if id == "" {
+		return "", errRunNotSelected
+	}
+
+	found, ok, err := runs.lookup(logDir, id)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errRunNotFound
+	}
+
+	return resolveInLogDir(logDir, found)
+}
+
+// resolveDir resolves the directory relative to the log directory
+func resolveDir(logDir string, dir string) (string, error) {
+	if dir == "" {
+		return logDir, nil
+	}
+
+	return resolveInLogDir(logDir, filepath.Join(logDir, filepath.FromSlash(dir)))
+}
+
+func runErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errRunNotSelected):
+		return http.StatusBadRequest
+	case errors.Is(err, errRunNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errOutsideLogDir):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeRunError(w http.ResponseWriter, err error) {
+	status := runErrorStatus(err)
+	http.Error(w, http.StatusText(status), status)
+}
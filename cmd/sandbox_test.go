@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRun(t *testing.T) {
+	root := t.TempDir()
+	logDir := filepath.Join(root, "logs")
+	assert.NoError(t, os.MkdirAll(filepath.Join(logDir, "1"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(logDir, "1", "access.log"), []byte("log"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0o644))
+	assert.NoError(t, os.Symlink(filepath.Join(root, "secret"), filepath.Join(logDir, "1", "secret.log")))
+
+	path, err := resolveRun(logDir, RunID(logDir, filepath.Join(logDir, "1", "access.log")))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(logDir, "1", "access.log"), path)
+
+	_, err = resolveRun(logDir, RunID(logDir, filepath.Join(logDir, "1", "secret.log")))
+	assert.ErrorIs(t, err, errOutsideLogDir)
+
+	// a symlink in the log directory resolves to the file it points to, which is the file checked
+	assert.NoError(t, os.Symlink(filepath.Join(logDir, "1", "access.log"), filepath.Join(logDir, "1", "link.log")))
+	path, err = resolveRun(logDir, RunID(logDir, filepath.Join(logDir, "1", "link.log")))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(logDir, "1", "access.log"), path)
+
+	_, err = resolveRun(logDir, "unknown")
+	assert.ErrorIs(t, err, errRunNotFound)
+
+	_, err = resolveRun(logDir, "")
+	assert.ErrorIs(t, err, errRunNotSelected)
+
+	_, err = resolveDir(logDir, "../")
+	assert.ErrorIs(t, err, errOutsideLogDir)
+
+	dir, err := resolveDir(logDir, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(logDir, "1"), dir)
+}
+
+func TestRunIndex(t *testing.T) {
+	logDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(logDir, "access.log"), []byte("log"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(logDir, metaTomlFile), []byte(""), 0o644))
+
+	index := &runIndex{}
+	path, ok, err := index.lookup(logDir, RunID(logDir, filepath.Join(logDir, "access.log")))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(logDir, "access.log"), path)
+	// sidecar files are not runs
+	assert.Len(t, index.paths, 1)
+
+	// a file added before the watcher notices is found by rebuilding the index
+	assert.NoError(t, os.WriteFile(filepath.Join(logDir, "slow.log"), []byte("log"), 0o644))
+	_, ok, err = index.lookup(logDir, RunID(logDir, filepath.Join(logDir, "slow.log")))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Len(t, index.paths, 2)
+
+	index.reset()
+	assert.Nil(t, index.paths)
+
+	_, ok, err = index.lookup(logDir, "unknown")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"html/template"
@@ -8,14 +9,16 @@ import (
 	"io/fs"
 	"log"
 	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/fsnotify/fsnotify"
 	"github.com/myuon/akari/akari"
 )
@@ -25,6 +28,7 @@ var (
 )
 
 type FileData struct {
+	ID         string
 	Name       string
 	Path       string
 	DirPath    string
@@ -34,6 +38,8 @@ type FileData struct {
 	Peek       []byte
 	LogType    string
 	PrevPath   string
+	PrevID     string
+	IsBaseline bool
 }
 
 func (d FileData) SizeHuman() string {
@@ -55,18 +61,29 @@ func (d FileData) ModifiedAtString() string {
 
 type PageDataFile struct {
 	DirPath string
+	// run directory relative to the log directory
+	Run     string
+	Meta    RunMeta
 	Content []FileData
 }
 
 type PageData struct {
 	Title string
 	Files []PageDataFile
+	// files which can be compared, newest first
+	Runs []FileData
 }
 
-func listFiles(root string) ([]FileData, error) {
+// listFiles lists the files under root, which is in logDir. Files linked to the outside of logDir are skipped.
+func listFiles(logDir string, root string) ([]FileData, error) {
 	var files []FileData
 	if err := filepath.WalkDir(root, func(path string, info os.DirEntry, _ error) error {
-		if info.IsDir() {
+		if info.IsDir() || isMetaFile(info.Name()) {
+			return nil
+		}
+
+		if err := checkInLogDir(logDir, path); err != nil {
+			slog.Debug("Skipped file", "path", path, "error", err)
 			return nil
 		}
 
@@ -104,6 +121,7 @@ func listFiles(root string) ([]FileData, error) {
 		}
 
 		files = append(files, FileData{
+			ID:         RunID(logDir, path),
 			Name:       info.Name(),
 			Path:       path,
 			IsDir:      info.IsDir(),
@@ -122,19 +140,11 @@ func listFiles(root string) ([]FileData, error) {
 	return files, nil
 }
 
-func logGroupHandler(w http.ResponseWriter, r *http.Request) {
-	serverData := UseServerData(r)
-
-	dir := r.URL.Query().Get("dir")
-	if dir == "" {
-		dir = serverData.LogDir
-	}
-
-	files, err := listFiles(dir)
+// listLogFiles lists the files with the file to compare with
+func listLogFiles(logDir string, dir string) ([]FileData, error) {
+	files, err := listFiles(logDir, dir)
 	if err != nil {
-		http.Error(w, "Failed to list files", http.StatusInternalServerError)
-		log.Println("Error listing files:", err)
-		return
+		return nil, err
 	}
 
 	slices.SortFunc(files, func(a, b FileData) int {
@@ -152,11 +162,49 @@ func logGroupHandler(w http.ResponseWriter, r *http.Request) {
 		for j := i + 1; j < len(files); j++ {
 			if files[j].LogType == file.LogType {
 				files[j].PrevPath = file.Path
+				files[j].PrevID = file.ID
 				break
 			}
 		}
 	}
 
+	// pinned baseline file for each log type, compared with all the other runs
+	pinned := readBaselines(logDir)
+	for i, file := range files {
+		baseline, ok := pinned[file.LogType]
+		if ok && baseline != file.Path {
+			files[i].PrevPath = baseline
+			files[i].PrevID = RunID(logDir, baseline)
+		}
+		files[i].IsBaseline = ok && baseline == file.Path
+	}
+
+	return files, nil
+}
+
+func logGroupHandler(w http.ResponseWriter, r *http.Request) {
+	serverData := UseServerData(r)
+
+	dir, err := resolveDir(serverData.LogDir, r.URL.Query().Get("dir"))
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+
+	files, err := listLogFiles(serverData.LogDir, dir)
+	if err != nil {
+		http.Error(w, "Failed to list files", http.StatusInternalServerError)
+		log.Println("Error listing files:", err)
+		return
+	}
+
+	runs := []FileData{}
+	for _, file := range files {
+		if file.LogType != "unknown" {
+			runs = append(runs, file)
+		}
+	}
+
 	filesByDirPath := map[string][]FileData{}
 	for _, file := range files {
 		filesByDirPath[file.DirPath] = append(filesByDirPath[file.DirPath], file)
@@ -166,6 +214,8 @@ func logGroupHandler(w http.ResponseWriter, r *http.Request) {
 	for dirPath, files := range filesByDirPath {
 		entries = append(entries, PageDataFile{
 			DirPath: dirPath,
+			Run:     runName(serverData.LogDir, dirPath),
+			Meta:    readRunMeta(dirPath),
 			Content: files,
 		})
 	}
@@ -176,6 +226,7 @@ func logGroupHandler(w http.ResponseWriter, r *http.Request) {
 	pageData := PageData{
 		Title: "Akari",
 		Files: entries,
+		Runs:  runs,
 	}
 	if err = serverData.TemplateFiles.ExecuteTemplate(w, "files.html", pageData); err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
@@ -184,10 +235,123 @@ func logGroupHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func rawFileHandler(w http.ResponseWriter, r *http.Request) {
-	filePath := r.URL.Query().Get("file")
+func detectLogType(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	line, err := peekLine(file)
+	if err != nil {
+		return "", err
+	}
+
+	analyzer, ok := detectAnalyzer(config.Load(), line)
+	if !ok {
+		return "", fmt.Errorf("no analyzer matched the log file: %v", path)
+	}
+
+	return analyzer.Name, nil
+}
+
+// compareHandler shows the difference between any two runs of the same log type
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	serverData := UseServerData(r)
+
+	baseID := r.URL.Query().Get("base")
+	basePath, err := resolveRun(serverData.LogDir, baseID)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+
+	targetID := r.URL.Query().Get("target")
+	targetPath, err := resolveRun(serverData.LogDir, targetID)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+
+	baseType, err := detectLogType(basePath)
+	if err != nil {
+		http.Error(w, "Failed to detect the log type of the base", http.StatusBadRequest)
+		return
+	}
+
+	targetType, err := detectLogType(targetPath)
+	if err != nil {
+		http.Error(w, "Failed to detect the log type of the target", http.StatusBadRequest)
+		return
+	}
+
+	if baseType != targetType {
+		http.Error(w, "Base and target have different log types", http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/view?"+url.Values{
+		"type": {targetType},
+		"file": {targetID},
+		"prev": {baseID},
+	}.Encode(), http.StatusSeeOther)
+}
+
+// baselineHandler pins the file as the baseline of its log type. An empty file unpins it.
+func baselineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	logType := r.FormValue("type")
+	if logType == "" {
+		http.Error(w, "Type not specified", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := findAnalyzer(config.Load(), logType); !ok {
+		http.Error(w, "Unknown log type", http.StatusBadRequest)
+		return
+	}
+
+	filePath := ""
+	if id := r.FormValue("file"); id != "" {
+		path, err := resolveRun(UseServerData(r).LogDir, id)
+		if err != nil {
+			writeRunError(w, err)
+			return
+		}
+
+		// the baseline is parsed by the analyzer of the type on every later view
+		fileType, err := detectLogType(path)
+		if err != nil || fileType != logType {
+			http.Error(w, "The file is not a log of the type", http.StatusBadRequest)
+			return
+		}
+
+		filePath = path
+	}
+
+	if err := pinBaseline(UseServerData(r).LogDir, logType, filePath); err != nil {
+		http.Error(w, "Failed to save baseline", http.StatusInternalServerError)
+		slog.Error("Failed to save baseline", "error", err)
+		return
+	}
+
 	if filePath == "" {
-		http.Error(w, "File not specified", http.StatusBadRequest)
+		slog.Info("Unpinned baseline", "type", logType)
+	} else {
+		slog.Info("Pinned baseline", "type", logType, "path", filePath)
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func rawFileHandler(w http.ResponseWriter, r *http.Request) {
+	filePath, err := resolveRun(UseServerData(r).LogDir, r.URL.Query().Get("file"))
+	if err != nil {
+		writeRunError(w, err)
 		return
 	}
 
@@ -210,65 +374,78 @@ func viewFileHandler(w http.ResponseWriter, r *http.Request) {
 		logType = "nginx"
 	}
 
-	filePath := r.URL.Query().Get("file")
-	if filePath == "" {
-		http.Error(w, "File not specified", http.StatusBadRequest)
-		return
-	}
+	serverData := UseServerData(r)
 
-	logFile, err := os.Open(filePath)
+	fileID := r.URL.Query().Get("file")
+	filePath, err := resolveRun(serverData.LogDir, fileID)
 	if err != nil {
-		http.Error(w, "Failed to open file", http.StatusInternalServerError)
+		writeRunError(w, err)
 		return
 	}
 
-	prevFilePath := r.URL.Query().Get("prev")
-
-	hasPrev := true
-	prevLogFile, err := os.Open(prevFilePath)
-	if err != nil {
-		slog.Warn("Failed to open previous file", "error", err)
-		hasPrev = false
+	prevID := r.URL.Query().Get("prev")
+	prevFilePath := ""
+	if prevID != "" {
+		prevFilePath, err = resolveRun(serverData.LogDir, prevID)
+		if err != nil {
+			writeRunError(w, err)
+			return
+		}
 	}
 
-	serverData := UseServerData(r)
-
 	tableData := akari.HtmlTableData{}
+	goneTableData := akari.HtmlTableData{}
 	usedAnalyzer := akari.AnalyzerConfig{}
+	outliersColumn := ""
+	outliers := []GroupOutliers{}
 	for _, analyzer := range config.Load().Analyzers {
 		if logType == analyzer.Name {
 			usedAnalyzer = analyzer
 
-			result, err := akari.Analyze(akari.AnalyzeOptions{
-				Config:  analyzer,
-				Source:  logFile,
-				HasPrev: hasPrev,
-				Prev:    prevLogFile,
-				Logger:  slog.Default(),
-				Seed:    serverData.HashSeed,
-			})
+			result, err := serverData.analyze(analyzer, filePath, prevFilePath)
 			if err != nil {
 				http.Error(w, "Failed to analyze log", http.StatusInternalServerError)
+				slog.Error("Failed to analyze log", "error", err)
 				return
 			}
 
+			if result.Outliers != nil {
+				outliersColumn = result.Outliers.Column
+				for _, row := range result.Rows {
+					if rowOutliers := result.Outliers.Rows[row.Key]; len(rowOutliers) > 0 {
+						outliers = append(outliers, GroupOutliers{
+							Key:      row.Key,
+							Group:    akari.GroupValues(result.GroupingKeys, row.Group),
+							Outliers: rowOutliers,
+						})
+					}
+				}
+			}
+
 			tableData = result.Html(akari.HtmlOptions{
 				ShowRank:    analyzer.ShowRank,
 				DiffHeaders: analyzer.Diffs,
 			})
+			goneTableData = result.GoneTable().Html(akari.HtmlOptions{})
 			break
 		}
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err = serverData.TemplateFiles.ExecuteTemplate(w, "view.html", map[string]any{
-		"Title":     filePath,
-		"PrevPath":  prevFilePath,
-		"LogType":   logType,
-		"Config":    usedAnalyzer,
-		"TableData": tableData,
-		"toStyle":   akari.HtmlStyle,
-		"toAttrs":   akari.HtmlAttrs,
+	if err := serverData.TemplateFiles.ExecuteTemplate(w, "view.html", map[string]any{
+		"Title":          runName(serverData.LogDir, filePath),
+		"FileID":         fileID,
+		"Meta":           readRunMeta(filepath.Dir(filePath)),
+		"PrevID":         prevID,
+		"LogType":        logType,
+		"Config":         usedAnalyzer,
+		"TableData":      tableData,
+		"GoneTableData":  goneTableData,
+		"OtherGroupKey":  akari.OtherGroupKey,
+		"OutliersColumn": outliersColumn,
+		"Outliers":       outliers,
+		"toStyle":        akari.HtmlStyle,
+		"toAttrs":        akari.HtmlAttrs,
 	}); err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Println("Template execution error:", err)
@@ -276,15 +453,45 @@ func viewFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type GroupOutliers struct {
+	Key      string
+	Group    []akari.GroupValue
+	Outliers []akari.Outlier
+}
+
+const filterPageSize = 100
+
+type FilterHeader struct {
+	Name    string
+	SortURL string
+	// "asc", "desc" or empty
+	Sort string
+}
+
+type FilterRow struct {
+	Line  akari.LogLine
+	Cells []string
+}
+
+type FilterPagination struct {
+	Page    int
+	Pages   int
+	Records int
+	PrevURL string
+	NextURL string
+}
+
 func filterViewHandler(w http.ResponseWriter, r *http.Request) {
 	logType := r.URL.Query().Get("type")
 	if logType == "" {
 		logType = "nginx"
 	}
 
-	filePath := r.URL.Query().Get("file")
-	if filePath == "" {
-		http.Error(w, "File not specified", http.StatusBadRequest)
+	serverData := UseServerData(r)
+
+	filePath, err := resolveRun(serverData.LogDir, r.URL.Query().Get("file"))
+	if err != nil {
+		writeRunError(w, err)
 		return
 	}
 
@@ -293,6 +500,7 @@ func filterViewHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to open file", http.StatusInternalServerError)
 		return
 	}
+	defer logFile.Close()
 
 	key := r.URL.Query().Get("key")
 	if key == "" {
@@ -300,19 +508,21 @@ func filterViewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serverData := UseServerData(r)
-
 	columns := akari.LogRecordColumns{}
 	filtered := akari.LogRecordRows{}
+	entries := []akari.LogEntry{}
+	group := []akari.GroupValue{}
 	usedAnalyzer := akari.AnalyzerConfig{}
 	for _, analyzer := range config.Load().Analyzers {
 		if logType == analyzer.Name {
 			usedAnalyzer = analyzer
 
-			parseOptions, err := analyzer.ParseOptions(serverData.HashSeed)
+			parseOptions, err := analyzer.ParseOptions()
 			if err != nil {
 				http.Error(w, "Failed to get parse options", http.StatusInternalServerError)
+				return
 			}
+			parseOptions.KeepLines = true
 
 			parsed, err := akari.Parse(parseOptions, logFile, slog.Default())
 			if err != nil {
@@ -321,94 +531,337 @@ func filterViewHandler(w http.ResponseWriter, r *http.Request) {
 			}
 
 			filtered = parsed.Records[key]
+			entries = parsed.Entries(key)
 			columns = parsed.Columns
+			group = akari.GroupValues(parsed.GroupingKeys, parsed.Groups[key])
 			break
 		}
 	}
 
-	_ = usedAnalyzer
+	sortKey := r.URL.Query().Get("sort")
+	sortColumn := strings.TrimPrefix(sortKey, "-")
+	if index := columns.GetIndex(sortColumn); index >= 0 {
+		akari.SortLogEntries(entries, index, strings.HasPrefix(sortKey, "-"))
+	}
 
-	groupByTimestamp := map[string]akari.LogRecordRows{}
-	for _, record := range filtered {
-		timestamp := record[columns.GetIndex("Timestamp")]
-		if timestamp == nil {
-			continue
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pages := max((len(entries)+filterPageSize-1)/filterPageSize, 1)
+	page = min(page, pages)
+
+	// links keep the other parameters of the page
+	pageURL := func(values map[string]string) string {
+		query := r.URL.Query()
+		for key, value := range values {
+			if value == "" {
+				query.Del(key)
+			} else {
+				query.Set(key, value)
+			}
 		}
 
-		timestampStr := fmt.Sprintf("%v", timestamp)
-		groupByTimestamp[timestampStr] = append(groupByTimestamp[timestampStr], record)
+		return "/filter?" + query.Encode()
 	}
 
-	maxCount := 0
-	entries := []struct {
-		Timestamp string
-		Records   akari.LogRecordRows
-	}{}
-	for timestamp, records := range groupByTimestamp {
-		entries = append(entries, struct {
-			Timestamp string
-			Records   akari.LogRecordRows
-		}{
-			Timestamp: timestamp,
-			Records:   records,
-		})
+	headers := []FilterHeader{}
+	for _, column := range columns {
+		// none -> descending -> ascending, and the page is reset
+		next := "-" + column.Name
+		if sortKey == next {
+			next = column.Name
+		}
 
-		if len(records) > maxCount {
-			maxCount = len(records)
+		header := FilterHeader{
+			Name:    column.Name,
+			SortURL: pageURL(map[string]string{"sort": next, "page": ""}),
 		}
+		if sortColumn == column.Name {
+			header.Sort = "asc"
+			if strings.HasPrefix(sortKey, "-") {
+				header.Sort = "desc"
+			}
+		}
+
+		headers = append(headers, header)
 	}
 
-	slices.SortStableFunc(entries, func(a, b struct {
-		Timestamp string
-		Records   akari.LogRecordRows
-	}) int {
-		return strings.Compare(a.Timestamp, b.Timestamp)
-	})
+	rows := []FilterRow{}
+	for _, entry := range entries[(page-1)*filterPageSize : min(page*filterPageSize, len(entries))] {
+		cells := []string{}
+		for _, value := range entry.Record {
+			if t, ok := value.(time.Time); ok {
+				value = t.Format(time.RFC3339Nano)
+			}
 
-	tableHeaders := []akari.HtmlTableHeader{}
-	tableHeaders = append(tableHeaders, akari.HtmlTableHeader{
-		Text: "Count",
-		Attributes: map[string]string{
-			"data-colorize": fmt.Sprintf("%v", maxCount),
-		},
-	})
+			cells = append(cells, fmt.Sprintf("%v", value))
+		}
+
+		rows = append(rows, FilterRow{
+			Line:  entry.Line,
+			Cells: cells,
+		})
+	}
+
+	pagination := FilterPagination{
+		Page:    page,
+		Pages:   pages,
+		Records: len(entries),
+	}
+	if page > 1 {
+		pagination.PrevURL = pageURL(map[string]string{"page": strconv.Itoa(page - 1)})
+	}
+	if page < pages {
+		pagination.NextURL = pageURL(map[string]string{"page": strconv.Itoa(page + 1)})
+	}
+
+	chartColumn := cmp.Or(r.URL.Query().Get("column"), usedAnalyzer.LatencyColumn())
+	numericColumns := []string{}
 	for _, column := range columns {
-		tableHeaders = append(tableHeaders, akari.HtmlTableHeader{
-			Text: column.Name,
+		if column.Type.IsNumeric() {
+			numericColumns = append(numericColumns, column.Name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err = serverData.TemplateFiles.ExecuteTemplate(w, "filter.html", map[string]any{
+		"Title":          runName(serverData.LogDir, filePath),
+		"LogType":        logType,
+		"Config":         usedAnalyzer,
+		"Group":          group,
+		"FileID":         r.URL.Query().Get("file"),
+		"PrevID":         r.URL.Query().Get("prev"),
+		"Key":            key,
+		"ChartColumn":    chartColumn,
+		"NumericColumns": numericColumns,
+		"Charts":         filterCharts(columns, filtered, chartColumn),
+		"Headers":        headers,
+		"RecordColumns":  len(headers) + 1,
+		"Rows":           rows,
+		"Pagination":     pagination,
+	}); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Println("Template execution error:", err)
+		return
+	}
+}
+
+// filterCharts draws the distribution of the column, and the requests and the p95 of the column over time if the records have timestamps
+func filterCharts(columns akari.LogRecordColumns, records akari.LogRecordRows, column string) []TrendChart {
+	valueIndex := columns.GetIndex(column)
+	if valueIndex < 0 || !columns[valueIndex].Type.IsNumeric() {
+		return nil
+	}
+	timestampIndex := columns.GetIndex("Timestamp")
+
+	values := []float64{}
+	timedValues := []float64{}
+	times := []time.Time{}
+	for _, record := range records {
+		// NaN and Inf can be neither binned nor plotted
+		value, ok := akari.NumericValue(record[valueIndex])
+		if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		values = append(values, value)
+
+		if timestampIndex < 0 {
+			continue
+		}
+		if t, ok := akari.TimestampValue(record[timestampIndex]); ok {
+			times = append(times, t)
+			timedValues = append(timedValues, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	options := func(title string) akari.ChartOptions {
+		return akari.ChartOptions{Title: title, Width: 360, Height: 180}
+	}
+
+	bins := akari.Histogram(values, 20)
+	charts := []TrendChart{
+		{Column: column, Svg: akari.BarChart(akari.HistogramPoints(bins), options(fmt.Sprintf("Distribution of %v", column)))},
+		{Column: column, Svg: akari.LineChart(akari.CumulativePoints(bins), options(fmt.Sprintf("CDF of %v", column)))},
+	}
+
+	buckets, interval := akari.Timeline(times, timedValues, 60)
+	if len(buckets) == 0 {
+		return charts
+	}
+
+	requests := []akari.ChartPoint{}
+	p95 := []akari.ChartPoint{}
+	for _, bucket := range buckets {
+		label := bucket.Start.Format("15:04:05")
+		requests = append(requests, akari.ChartPoint{Label: label, Value: float64(len(bucket.Values))})
+		if len(bucket.Values) > 0 {
+			p95 = append(p95, akari.ChartPoint{Label: label, Value: akari.GetPercentile(bucket.Values, 95)})
+		}
+	}
+
+	return append(charts,
+		TrendChart{Column: "Requests", Svg: akari.LineChart(requests, options(fmt.Sprintf("Requests per %v", interval)))},
+		TrendChart{Column: column, Svg: akari.LineChart(p95, options(fmt.Sprintf("P95 of %v per %v", column, interval)))},
+	)
+}
+
+// summarizeRuns summarizes every run of the analyzer without the previous file, from the oldest to the newest
+func (d ServerData) summarizeRuns(analyzer akari.AnalyzerConfig) ([]akari.TrendRun, error) {
+	files, err := listRuns(d.LogDir, analyzer.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	runs := []akari.TrendRun{}
+	for _, file := range files {
+		summary, err := d.summarize(analyzer, file.Path, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze %v: %w", file.Path, err)
+		}
+
+		runs = append(runs, akari.TrendRun{
+			Name:    runName(d.LogDir, file.Path),
+			Summary: summary,
+			Score:   readRunMeta(file.DirPath).Score,
 		})
 	}
 
-	tableRows := []akari.HtmlTableRow{}
-	for _, entry := range entries {
-		cells := []akari.HtmlTableCell{}
+	return runs, nil
+}
+
+type TrendChart struct {
+	Column string
+	Svg    template.HTML
+}
+
+// listRuns returns the log files of the analyzer, from the oldest run to the newest
+func listRuns(logDir string, logType string) ([]FileData, error) {
+	files, err := listFiles(logDir, logDir)
+	if err != nil {
+		return nil, err
+	}
 
-		row := entry.Records[0]
-		cells = append(cells, akari.HtmlTableCell{
-			Text: template.HTML(fmt.Sprintf("%v", len(entry.Records))),
-			Attributes: map[string]string{
-				"data-value": fmt.Sprintf("%v", len(entry.Records)),
-			},
+	files = slices.DeleteFunc(files, func(file FileData) bool {
+		return file.LogType != logType
+	})
+	slices.SortFunc(files, func(a, b FileData) int {
+		if a.DirPath != b.DirPath {
+			return strings.Compare(a.DirPath, b.DirPath)
+		} else if !a.ModifiedAt.Equal(b.ModifiedAt) {
+			return a.ModifiedAt.Compare(b.ModifiedAt)
+		} else {
+			return strings.Compare(a.Name, b.Name)
+		}
+	})
+
+	return files, nil
+}
+
+// runName is the path relative to the log directory, which is shown instead of the path
+func runName(logDir string, path string) string {
+	if path == "" {
+		return ""
+	}
+
+	name, err := filepath.Rel(logDir, path)
+	if err != nil {
+		return path
+	}
+
+	return filepath.ToSlash(name)
+}
+
+func trendViewHandler(w http.ResponseWriter, r *http.Request) {
+	logType := r.URL.Query().Get("type")
+	if logType == "" {
+		logType = "nginx"
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Key not specified", http.StatusBadRequest)
+		return
+	}
+
+	serverData := UseServerData(r)
+
+	analyzer, ok := findAnalyzer(config.Load(), logType)
+	if !ok {
+		http.Error(w, "Unknown log type", http.StatusBadRequest)
+		return
+	}
+
+	runs, err := serverData.summarizeRuns(analyzer)
+	if err != nil {
+		http.Error(w, "Failed to analyze logs", http.StatusInternalServerError)
+		slog.Error("Failed to analyze logs", "error", err)
+		return
+	}
+
+	trend, err := akari.Trend(analyzer, runs, key)
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+
+	charts := []TrendChart{}
+
+	// the score is charted next to the metrics, for the runs which have the group
+	scores := map[string]float64{}
+	for _, run := range runs {
+		if run.Score != nil {
+			scores[run.Name] = *run.Score
+		}
+	}
+
+	scorePoints := []akari.ChartPoint{}
+	for _, row := range trend.Rows {
+		if score, ok := scores[row.Key]; ok {
+			scorePoints = append(scorePoints, akari.ChartPoint{Label: row.Key, Value: score})
+		}
+	}
+	if len(scorePoints) > 0 {
+		charts = append(charts, TrendChart{
+			Column: "Score",
+			Svg: akari.LineChart(scorePoints, akari.ChartOptions{
+				Title:  "Score",
+				Width:  360,
+				Height: 180,
+			}),
 		})
-		for _, column := range columns {
-			cells = append(cells, akari.HtmlTableCell{
-				Text: template.HTML(fmt.Sprintf("%v", row[columns.GetIndex(column.Name)])),
-			})
+	}
+
+	for _, column := range trend.Columns {
+		points, ok := trend.Series(column.Name)
+		if !ok {
+			continue
 		}
 
-		tableRows = append(tableRows, akari.HtmlTableRow{
-			Cells: cells,
+		charts = append(charts, TrendChart{
+			Column: column.Name,
+			Svg: akari.LineChart(points, akari.ChartOptions{
+				Title:  column.Name,
+				Width:  360,
+				Height: 180,
+			}),
 		})
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err = serverData.TemplateFiles.ExecuteTemplate(w, "filter.html", map[string]any{
-		"Title":   filePath,
+	if err := serverData.TemplateFiles.ExecuteTemplate(w, "trend.html", map[string]any{
+		"Title":   key,
 		"LogType": logType,
-		"Config":  usedAnalyzer,
-		"TableData": akari.HtmlTableData{
-			Headers: tableHeaders,
-			Rows:    tableRows,
-		},
+		"Runs":    len(runs),
+		"Group":   akari.GroupValues(trend.GroupingKeys, trend.Rows[0].Group),
+		"Charts":  charts,
+		"TableData": trend.Html(akari.HtmlOptions{
+			DiffHeaders: analyzer.Diffs,
+		}),
 		"toStyle": akari.HtmlStyle,
 		"toAttrs": akari.HtmlAttrs,
 	}); err != nil {
@@ -424,8 +877,21 @@ const contextKey ContextKey = "serverData"
 
 type ServerData struct {
 	TemplateFiles *template.Template
-	HashSeed      uint64
 	LogDir        string
+	Store         *akari.SnapshotStore
+	Changes       *changeHub
+	Cache         *analysisCache
+	AllowUpload   bool
+	MaxUploadSize int64
+}
+
+// summarizeFile uses the snapshot store if it is enabled
+func (d ServerData) summarizeFile(options akari.SummarizeFileOptions) (akari.SummaryRecords, error) {
+	if d.Store == nil {
+		return akari.SummarizeFile(options)
+	}
+
+	return d.Store.SummarizeFile(options)
 }
 
 func withServerData(next http.Handler, data ServerData) http.Handler {
@@ -444,7 +910,10 @@ type ServeOptions struct {
 	LogDir        string
 	TemplateFiles *template.Template
 	PublicFS      fs.FS
-	HashSeed      uint64
+	StoreDir      string
+	// uploads are refused unless auth is configured or AllowUpload is set
+	AllowUpload   bool
+	MaxUploadSize int64
 	Port          int
 	Hostname      string
 }
@@ -456,11 +925,20 @@ func Serve(options ServeOptions) error {
 	}
 	defer watcher.Close()
 
+	changes := newChangeHub()
+	cache := newAnalysisCache()
+
 	go func() {
 		for event := range watcher.Events {
+			if event.Name != options.ConfigFile && inDir(options.LogDir, event.Name) {
+				handleLogDirEvent(watcher, changes, event)
+				continue
+			}
+
 			if event.Name == options.ConfigFile {
-				var c akari.AkariConfig
-				if _, err := toml.DecodeFile(options.ConfigFile, &c); err != nil {
+				// a broken config keeps the previous one, including its auth
+				c, err := loadServerConfig(options.ConfigFile)
+				if err != nil {
 					slog.Error("Failed to load config", "error", err)
 					continue
 				}
@@ -468,13 +946,16 @@ func Serve(options ServeOptions) error {
 				slog.Info("Config reloaded")
 
 				config.Store(c)
+				cache.clear()
+				runs.reset()
 			}
 		}
 	}()
 
-	var c akari.AkariConfig
-	if _, err := toml.DecodeFile(options.ConfigFile, &c); err != nil {
-		slog.Error("Failed to load config", "error", err)
+	// the server does not start without its auth config
+	c, err := loadServerConfig(options.ConfigFile)
+	if err != nil {
+		return err
 	}
 
 	config.Store(c)
@@ -485,6 +966,16 @@ func Serve(options ServeOptions) error {
 
 	slog.Debug("Loaded config", "path", options.ConfigFile, "config", config)
 
+	if err := watchDirs(watcher, options.LogDir); err != nil {
+		slog.Error("Failed to watch log directory", "error", err)
+	}
+
+	var store *akari.SnapshotStore
+	if options.StoreDir != "" {
+		store = akari.NewSnapshotStore(options.StoreDir)
+		slog.Debug("Using snapshot store", "path", options.StoreDir)
+	}
+
 	mux := http.NewServeMux()
 
 	mux.Handle("/public/", http.FileServer(http.FS(options.PublicFS)))
@@ -492,15 +983,35 @@ func Serve(options ServeOptions) error {
 	mux.HandleFunc("/raw", rawFileHandler)
 	mux.HandleFunc("/view", viewFileHandler)
 	mux.HandleFunc("/filter", filterViewHandler)
+	mux.HandleFunc("/trend", trendViewHandler)
+	mux.HandleFunc("/correlation", correlationViewHandler)
+	mux.HandleFunc("/related", relatedViewHandler)
+	mux.HandleFunc("/compare", compareHandler)
+	mux.HandleFunc("/baseline", baselineHandler)
+	mux.HandleFunc("/meta", metaHandler)
+	mux.HandleFunc("/upload", uploadHandler)
+	mux.HandleFunc("/upload/", uploadHandler)
+	mux.HandleFunc("/events", eventsHandler)
+	mux.HandleFunc("/api/runs", apiRunsHandler)
+	mux.HandleFunc("/api/analyze", apiAnalyzeHandler)
+	mux.HandleFunc("/api/filter", apiFilterHandler)
+
+	if authConfig().Enabled() {
+		slog.Info("Authentication enabled")
+	}
 
 	slog.Info("Starting server", "url", fmt.Sprintf("http://%v:%v", options.Hostname, options.Port))
 
 	if err := http.ListenAndServe(
 		fmt.Sprintf("%v:%v", options.Hostname, options.Port),
-		withServerData(mux, ServerData{
+		withServerData(withAuth(mux), ServerData{
 			TemplateFiles: options.TemplateFiles,
-			HashSeed:      options.HashSeed,
 			LogDir:        options.LogDir,
+			Store:         store,
+			Changes:       changes,
+			Cache:         cache,
+			AllowUpload:   options.AllowUpload,
+			MaxUploadSize: options.MaxUploadSize,
 		}),
 	); err != nil {
 		slog.Error("Failed to start server", "error", err)
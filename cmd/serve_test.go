@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"html/template"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/myuon/akari/akari"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterCharts(t *testing.T) {
+	columns := akari.LogRecordColumns{
+		{Name: "Timestamp", Type: akari.LogRecordTypeString},
+		{Name: "ResponseTime", Type: akari.LogRecordTypeFloat64},
+	}
+	records := akari.LogRecordRows{
+		{"03/Jan/2025:13:29:01 +0900", 0.1},
+		{"03/Jan/2025:13:29:02 +0900", math.NaN()},
+		{"03/Jan/2025:13:29:03 +0900", math.Inf(1)},
+		{"03/Jan/2025:13:29:04 +0900", 0.3},
+	}
+
+	charts := filterCharts(columns, records, "ResponseTime")
+	assert.Len(t, charts, 4)
+	for _, chart := range charts {
+		assert.NotContains(t, string(chart.Svg), "NaN")
+		assert.NotContains(t, string(chart.Svg), "Inf")
+	}
+
+	// no finite values, no charts
+	assert.Empty(t, filterCharts(columns, records[1:3], "ResponseTime"))
+}
+
+func TestTrendViewHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 3)
+	for i := 1; i <= 3; i++ {
+		score := float64(i * 100)
+		assert.NoError(t, saveRunMeta(filepath.Join(logDir, strconv.Itoa(i)), RunMeta{Score: &score}))
+	}
+
+	key, err := akari.GroupKey([]any{"GET", "/api/a", "HTTP/1.1"})
+	assert.NoError(t, err)
+
+	serverData := ServerData{
+		LogDir:        logDir,
+		TemplateFiles: template.Must(template.ParseFS(os.DirFS(".."), "templates/*.html")),
+		Cache:         newAnalysisCache(),
+	}
+	trend := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/trend?"+url.Values{"type": {"nginx"}, "key": {key}}.Encode(), nil)
+		w := httptest.NewRecorder()
+		trendViewHandler(w, r.WithContext(context.WithValue(r.Context(), contextKey, serverData)))
+		return w
+	}
+
+	w := trend(key)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "3 of 3 runs have this group")
+	assert.Contains(t, w.Body.String(), ">Score</text>")
+	assert.Contains(t, w.Body.String(), "<title>3/access.log: 300</title>")
+	// the runs are summarized once, and the other requests reuse them
+	assert.Len(t, serverData.Cache.entries, 3)
+
+	w = trend(`["GET","/unknown","HTTP/1.1"]`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = trend("")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// a run whose response time cannot be parsed
+	broken := filepath.Join(logDir, "4", "access.log")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(broken), 0o755))
+	assert.NoError(t, os.WriteFile(broken, []byte(strings.ReplaceAll(testNginxLog, "0.011", "-")), 0o644))
+
+	w = trend(key)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
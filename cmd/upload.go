@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myuon/akari/akari"
+)
+
+type ApiUploadedFile struct {
+	ID      string
+	Run     string
+	Path    string
+	LogType string
+	ViewURL string
+}
+
+var errInvalidName = errors.New("invalid name")
+
+// the uploads being written are hidden from the file list and the watcher until they are complete
+const uploadTempPrefix = ".upload-"
+
+func isPartialUpload(name string) bool {
+	return strings.HasPrefix(name, uploadTempPrefix)
+}
+
+// validName accepts a single path element, so that uploads never escape the run directory
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// runDir returns the run directory to upload into, and whether it was created. Without the name, a new directory named by the current unix time is created.
+func runDir(logDir string, name string) (string, bool, error) {
+	if name == "" {
+		name = strconv.FormatInt(time.Now().Unix(), 10)
+	} else if !validName(name) {
+		return "", false, errInvalidName
+	}
+
+	dir := filepath.Join(logDir, name)
+	_, err := os.Stat(dir)
+	created := errors.Is(err, os.ErrNotExist)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", false, fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	if err := checkInLogDir(logDir, dir); err != nil {
+		return "", false, err
+	}
+
+	return dir, created, nil
+}
+
+func saveUpload(dir string, name string, body io.Reader) (string, error) {
+	if !validName(name) {
+		return "", errInvalidName
+	}
+
+	path := filepath.Join(dir, name)
+	exists := func() error {
+		if _, err := os.Lstat(path); err == nil {
+			return &fs.PathError{Op: "create", Path: path, Err: fs.ErrExist}
+		}
+		return nil
+	}
+	if err := exists(); err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first, so that nobody sees a partial log
+	file, err := os.CreateTemp(dir, uploadTempPrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	// another request may have saved the same name while this one was being written
+	if err := exists(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return path, nil
+}
+
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errInvalidName):
+		return http.StatusBadRequest
+	case errors.Is(err, os.ErrExist):
+		return http.StatusConflict
+	default:
+		return runErrorStatus(err)
+	}
+}
+
+// uploadHandler saves the log files into a run directory.
+// It accepts multipart forms with "file" fields (POST /upload), or a raw body with the file name in the path (PUT /upload/<name>, which is what `curl -T` sends).
+// Pass ?run=<name> to upload several files into the same run.
+// If any of the files fails, none of them are kept.
+func uploadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	serverData := UseServerData(r)
+
+	// anyone who can reach the server could fill up the disk
+	if !serverData.AllowUpload && !authConfig().Enabled() {
+		writeJSONError(w, http.StatusForbidden, "Uploads are disabled. Configure auth or pass --allow-upload")
+		return
+	}
+
+	if serverData.MaxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, serverData.MaxUploadSize)
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/upload/")
+	if r.Method == http.MethodPut && !validName(name) {
+		writeJSONError(w, http.StatusBadRequest, "File name must be given in the path")
+		return
+	}
+
+	var reader *multipart.Reader
+	if r.Method == http.MethodPost {
+		multipartReader, err := r.MultipartReader()
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Multipart form expected")
+			return
+		}
+
+		reader = multipartReader
+	}
+
+	dir, created, err := runDir(serverData.LogDir, r.URL.Query().Get("run"))
+	if err != nil {
+		writeJSONError(w, uploadErrorStatus(err), "Failed to create run directory")
+		slog.Error("Failed to create run directory", "error", err)
+		return
+	}
+
+	paths := []string{}
+	// fail removes the files saved by the request, and the run directory created for them
+	fail := func(status int, message string) {
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil {
+				slog.Error("Failed to remove upload", "path", path, "error", err)
+			}
+		}
+		if created {
+			os.Remove(dir)
+		}
+
+		writeJSONError(w, status, message)
+	}
+	save := func(name string, body io.Reader) bool {
+		path, err := saveUpload(dir, name, body)
+		if err != nil {
+			fail(uploadErrorStatus(err), fmt.Sprintf("Failed to save %v", name))
+			slog.Error("Failed to save upload", "name", name, "error", err)
+			return false
+		}
+
+		slog.Info("Uploaded file", "path", path)
+		paths = append(paths, path)
+		return true
+	}
+
+	if reader == nil {
+		if !save(name, r.Body) {
+			return
+		}
+	} else {
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				status := http.StatusBadRequest
+				if errors.As(err, new(*http.MaxBytesError)) {
+					status = http.StatusRequestEntityTooLarge
+				}
+
+				fail(status, "Failed to read multipart form")
+				return
+			}
+
+			if part.FormName() == "file" && !save(part.FileName(), part) {
+				return
+			}
+		}
+
+		if len(paths) == 0 {
+			fail(http.StatusBadRequest, "No file field in the form")
+			return
+		}
+	}
+
+	files, err := listLogFiles(serverData.LogDir, serverData.LogDir)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list files")
+		slog.Error("Failed to list files", "error", err)
+		return
+	}
+
+	result := []ApiUploadedFile{}
+	for _, file := range files {
+		if !slices.Contains(paths, file.Path) {
+			continue
+		}
+
+		result = append(result, ApiUploadedFile{
+			ID:      file.ID,
+			Run:     runName(serverData.LogDir, file.DirPath),
+			Path:    runName(serverData.LogDir, file.Path),
+			LogType: file.LogType,
+			ViewURL: "/view?" + url.Values{"type": {file.LogType}, "file": {file.ID}, "prev": {file.PrevID}}.Encode(),
+		})
+
+		if analyzer, ok := findAnalyzer(config.Load(), file.LogType); ok {
+			go analyzeUpload(serverData, analyzer, file)
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// analyzeUpload analyzes the uploaded file in background, so that the first view is served from the store
+func analyzeUpload(serverData ServerData, analyzer akari.AnalyzerConfig, file FileData) {
+	if _, err := serverData.summarizeFile(akari.SummarizeFileOptions{
+		Config:   analyzer,
+		LogPath:  file.Path,
+		PrevPath: file.PrevPath,
+		Logger:   slog.Default(),
+	}); err != nil {
+		slog.Error("Failed to analyze uploaded file", "path", file.Path, "error", err)
+		return
+	}
+
+	slog.Info("Analyzed uploaded file", "path", file.Path)
+}
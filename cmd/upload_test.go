@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testMultipartRequest posts the files in the order of the names
+func testMultipartRequest(t *testing.T, target string, files map[string]string, names ...string) *http.Request {
+	if len(names) == 0 {
+		for name := range files {
+			names = append(names, name)
+		}
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, name := range names {
+		part, err := writer.CreateFormFile("file", name)
+		assert.NoError(t, err)
+		_, err = part.Write([]byte(files[name]))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, target, body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r
+}
+
+func TestUploadHandler(t *testing.T) {
+	logDir := t.TempDir()
+
+	upload := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		uploadHandler(w, r.WithContext(context.WithValue(r.Context(), contextKey, ServerData{LogDir: logDir, AllowUpload: true})))
+		return w
+	}
+
+	w := upload(httptest.NewRequest(http.MethodPut, "/upload/access.log?run=1", strings.NewReader("nginx log")))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	content, err := os.ReadFile(filepath.Join(logDir, "1", "access.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx log", string(content))
+
+	info, err := os.Stat(filepath.Join(logDir, "1", "access.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	// existing files are not overwritten
+	w = upload(httptest.NewRequest(http.MethodPut, "/upload/access.log?run=1", strings.NewReader("other")))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	r := testMultipartRequest(t, "/upload?run=1", map[string]string{"mysql-slow.log": "mysql log"})
+	w = upload(r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	content, err = os.ReadFile(filepath.Join(logDir, "1", "mysql-slow.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "mysql log", string(content))
+
+	// no temporary file is left behind
+	names, err := os.ReadDir(filepath.Join(logDir, "1"))
+	assert.NoError(t, err)
+	assert.Len(t, names, 2)
+
+	w = upload(httptest.NewRequest(http.MethodPut, "/upload/access.log?run=..", strings.NewReader("log")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = upload(httptest.NewRequest(http.MethodPut, "/upload/", strings.NewReader("log")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPartialUploadHidden(t *testing.T) {
+	logDir := setupTestLogDir(t, 1)
+	assert.NoError(t, os.WriteFile(filepath.Join(logDir, "1", uploadTempPrefix+"123"), []byte(testNginxLog), 0o644))
+
+	files, err := listFiles(logDir, logDir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "access.log", files[0].Name)
+}
+
+func TestUploadHandlerLimits(t *testing.T) {
+	logDir := t.TempDir()
+
+	upload := func(serverData ServerData, r *http.Request) *httptest.ResponseRecorder {
+		serverData.LogDir = logDir
+		w := httptest.NewRecorder()
+		uploadHandler(w, r.WithContext(context.WithValue(r.Context(), contextKey, serverData)))
+		return w
+	}
+	entries := func() []string {
+		names := []string{}
+		assert.NoError(t, filepath.WalkDir(logDir, func(path string, entry fs.DirEntry, err error) error {
+			if path != logDir {
+				names = append(names, runName(logDir, path))
+			}
+			return err
+		}))
+		return names
+	}
+
+	// uploads are refused without auth unless allowed explicitly
+	w := upload(ServerData{}, httptest.NewRequest(http.MethodPut, "/upload/access.log?run=1", strings.NewReader("log")))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, entries())
+
+	w = upload(ServerData{AllowUpload: true, MaxUploadSize: 4}, httptest.NewRequest(http.MethodPut, "/upload/access.log?run=1", strings.NewReader("large log")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Empty(t, entries())
+
+	w = upload(ServerData{AllowUpload: true}, testMultipartRequest(t, "/upload?run=1", map[string]string{}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, entries())
+
+	// the files saved before a failure are removed
+	assert.NoError(t, os.MkdirAll(filepath.Join(logDir, "2"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(logDir, "2", "b.log"), []byte("existing"), 0o644))
+	r := testMultipartRequest(t, "/upload?run=2", map[string]string{"a.log": "a", "b.log": "b"}, "a.log", "b.log")
+	w = upload(ServerData{AllowUpload: true}, r)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, []string{"2", "2/b.log"}, entries())
+}
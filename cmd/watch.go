@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// changes are sent once the file stays unchanged for changeQuiet
+	changeQuiet = time.Second
+	// but a log written continuously is still sent every changeMaxDelay
+	changeMaxDelay = 10 * time.Second
+)
+
+type pendingChange struct {
+	since time.Time
+	timer *time.Timer
+}
+
+// changeHub sends the changed paths in the log directory to the subscribers.
+// The changes are debounced for each path, so that all the subscribers reload at once and share the analysis.
+type changeHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan string]struct{}
+	pending     map[string]*pendingChange
+	quiet       time.Duration
+	maxDelay    time.Duration
+}
+
+func newChangeHub() *changeHub {
+	return &changeHub{
+		subscribers: map[chan string]struct{}{},
+		pending:     map[string]*pendingChange{},
+		quiet:       changeQuiet,
+		maxDelay:    changeMaxDelay,
+	}
+}
+
+func (h *changeHub) subscribe() chan string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	ch := make(chan string, 16)
+	h.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+func (h *changeHub) unsubscribe(ch chan string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.subscribers, ch)
+}
+
+func (h *changeHub) publish(path string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if pending, ok := h.pending[path]; ok {
+		if time.Since(pending.since)+h.quiet < h.maxDelay {
+			pending.timer.Reset(h.quiet)
+		}
+		return
+	}
+
+	pending := &pendingChange{since: time.Now()}
+	pending.timer = time.AfterFunc(h.quiet, func() { h.send(path, pending) })
+	h.pending[path] = pending
+}
+
+func (h *changeHub) send(path string, pending *pendingChange) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// the timer reset while firing fires again
+	if h.pending[path] != pending {
+		return
+	}
+	delete(h.pending, path)
+
+	for ch := range h.subscribers {
+		// slow subscribers miss the event, which is fine because they reload the whole page anyway
+		select {
+		case ch <- path:
+		default:
+		}
+	}
+}
+
+// watchDirs watches the directory and its subdirectories, since fsnotify does not watch recursively
+func watchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if entry.IsDir() {
+			if err := watcher.Add(path); err != nil {
+				return fmt.Errorf("failed to watch %v: %w", path, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func inDir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// handleLogDirEvent publishes the change, and watches the directories created in the log directory
+func handleLogDirEvent(watcher *fsnotify.Watcher, hub *changeHub, event fsnotify.Event) {
+	// an upload is published once it is renamed into place
+	if isPartialUpload(filepath.Base(event.Name)) {
+		return
+	}
+
+	// appending to a file does not change the run IDs
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		runs.reset()
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := watchDirs(watcher, event.Name); err != nil {
+				slog.Error("Failed to watch directory", "error", err)
+			}
+		}
+	}
+
+	hub.publish(filepath.Clean(event.Name))
+}
+
+// eventsHandler notifies the changes in the log directory with Server-Sent Events.
+// If file is given, only the changes of the file are notified.
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	serverData := UseServerData(r)
+
+	filePath := ""
+	if id := r.URL.Query().Get("file"); id != "" {
+		path, err := resolveRun(serverData.LogDir, id)
+		if err != nil {
+			writeRunError(w, err)
+			return
+		}
+
+		filePath = filepath.Clean(path)
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	changes := serverData.Changes.subscribe()
+	defer serverData.Changes.unsubscribe(changes)
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case path := <-changes:
+			if filePath != "" && path != filePath {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: change\ndata: %v\n\n", RunID(serverData.LogDir, path)); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testChangeHub() *changeHub {
+	hub := newChangeHub()
+	hub.quiet = 50 * time.Millisecond
+	hub.maxDelay = 200 * time.Millisecond
+	return hub
+}
+
+func TestChangeHub(t *testing.T) {
+	hub := testChangeHub()
+
+	changes := hub.subscribe()
+
+	// a burst of writes is sent once
+	for range 3 {
+		hub.publish("/logs/access.log")
+	}
+	select {
+	case path := <-changes:
+		assert.Equal(t, "/logs/access.log", path)
+	case <-time.After(time.Second):
+		t.Fatal("change not sent")
+	}
+	select {
+	case path := <-changes:
+		t.Fatalf("change sent twice: %v", path)
+	case <-time.After(3 * hub.quiet):
+	}
+
+	// a log written continuously is still sent
+	sent := false
+	for start := time.Now(); time.Since(start) < 3*hub.maxDelay && !sent; {
+		hub.publish("/logs/access.log")
+		select {
+		case <-changes:
+			sent = true
+		case <-time.After(hub.quiet / 5):
+		}
+	}
+	assert.True(t, sent)
+
+	hub.unsubscribe(changes)
+	hub.publish("/logs/access.log")
+	select {
+	case path := <-changes:
+		t.Fatalf("change sent after unsubscribe: %v", path)
+	case <-time.After(3 * hub.quiet):
+	}
+	assert.Empty(t, hub.subscribers)
+}
+
+func TestEventsHandler(t *testing.T) {
+	logDir := setupTestLogDir(t, 2)
+	hub := testChangeHub()
+
+	server := httptest.NewServer(withServerData(http.HandlerFunc(eventsHandler), ServerData{LogDir: logDir, Changes: hub}))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/events?" + url.Values{"file": {testRunID(logDir, "2")}}.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, ": connected\n", line)
+
+	// only the changes of the file are sent
+	hub.publish(filepath.Join(logDir, "1", "access.log"))
+	hub.publish(filepath.Join(logDir, "2", "access.log"))
+
+	events := []string{}
+	for len(events) < 2 {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		if line = strings.TrimSpace(line); line != "" {
+			events = append(events, line)
+		}
+	}
+	assert.Equal(t, []string{"event: change", "data: " + testRunID(logDir, "2")}, events)
+
+	// the subscriber is removed when the client goes away
+	response.Body.Close()
+	assert.Eventually(t, func() bool {
+		hub.mutex.Lock()
+		defer hub.mutex.Unlock()
+		return len(hub.subscribers) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	response, err = http.Get(server.URL + "/events?file=unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	response.Body.Close()
+}
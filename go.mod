@@ -16,7 +16,6 @@ require (
 )
 
 require (
-	github.com/pierrec/xxHash v0.1.5 // indirect
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/sys v0.28.0 // indirect
 )
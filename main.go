@@ -7,6 +7,7 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/akamensky/argparse"
@@ -25,7 +26,6 @@ var publicFS embed.FS
 
 var (
 	templateFiles     = template.Must(template.ParseFS(templateFS, "templates/*.html"))
-	globalSeed        = uint64(0xdeadbeef)
 	defaultConfigPath = "akari.toml"
 )
 
@@ -33,35 +33,116 @@ type RunCommand struct {
 	Command    *argparse.Command
 	ConfigFile *string
 	LogFile    *string
+	Format     *string
+	FailOn     *[]string
+	Outliers   *int
+}
+
+func outputFormatNames() []string {
+	names := []string{}
+	for _, format := range akari.OutputFormats {
+		names = append(names, string(format))
+	}
+
+	return names
 }
 
 func NewRunCommand(parser *argparse.Parser) *RunCommand {
 	command := parser.NewCommand("run", "Run the log analyzer")
 	condfig := command.String("c", "akari.toml", &argparse.Options{Help: "Configuration file path"})
+	format := command.Selector("f", "format", outputFormatNames(), &argparse.Options{Help: "Output format", Default: string(akari.OutputFormatText)})
+	failOn := command.StringList("", "fail-on", &argparse.Options{Help: `Fails if any row matches the rule (e.g. "5xx > 0")`})
+	outliers := command.Int("", "outliers", &argparse.Options{Help: "Shows the N records with the largest values for each group (overrides outliers in the config)"})
 	file := command.StringPositional(nil)
 
 	return &RunCommand{
 		Command:    command,
 		ConfigFile: condfig,
 		LogFile:    file,
+		Format:     format,
+		FailOn:     failOn,
+		Outliers:   outliers,
 	}
 }
 
 type ServeCommand struct {
-	Command    *argparse.Command
-	ConfigFile *string
-	LogDir     *string
+	Command       *argparse.Command
+	ConfigFile    *string
+	StoreDir      *string
+	NoStore       *bool
+	AllowUpload   *bool
+	MaxUploadSize *int
+	LogDir        *string
 }
 
 func NewServeCommand(parser *argparse.Parser) *ServeCommand {
 	command := parser.NewCommand("serve", "Starts a web server to serve the log analyzer")
 	config := command.String("c", "akari.toml", &argparse.Options{Help: "Configuration file path"})
+	storeDir := command.String("", "store", &argparse.Options{Help: "Directory to store the analysis results (default: user cache directory)"})
+	noStore := command.Flag("", "no-store", &argparse.Options{Help: "Do not store the analysis results"})
+	allowUpload := command.Flag("", "allow-upload", &argparse.Options{Help: "Accept uploads without authentication"})
+	maxUploadSize := command.Int("", "max-upload-size", &argparse.Options{Help: "Maximum size of an upload request in MiB", Default: 1024})
 	logDir := command.StringPositional(nil)
 
 	return &ServeCommand{
+		Command:       command,
+		ConfigFile:    config,
+		StoreDir:      storeDir,
+		NoStore:       noStore,
+		AllowUpload:   allowUpload,
+		MaxUploadSize: maxUploadSize,
+		LogDir:        logDir,
+	}
+}
+
+type ReportCommand struct {
+	Command    *argparse.Command
+	ConfigFile *string
+	OutputFile *string
+	PrevFile   *string
+	LogFile    *string
+}
+
+func NewReportCommand(parser *argparse.Parser) *ReportCommand {
+	command := parser.NewCommand("report", "Generates a self-contained HTML report")
+	config := command.String("c", "config", &argparse.Options{Help: "Configuration file path"})
+	output := command.String("o", "output", &argparse.Options{Help: "Output file path", Default: "report.html"})
+	prev := command.String("p", "prev", &argparse.Options{Help: "Previous log file to compare with"})
+	file := command.StringPositional(nil)
+
+	return &ReportCommand{
 		Command:    command,
 		ConfigFile: config,
-		LogDir:     logDir,
+		OutputFile: output,
+		PrevFile:   prev,
+		LogFile:    file,
+	}
+}
+
+type DiffCommand struct {
+	Command    *argparse.Command
+	ConfigFile *string
+	Color      *bool
+	FailOn     *[]string
+	PrevFile   *string
+	LogFile    *string
+}
+
+func NewDiffCommand(parser *argparse.Parser) *DiffCommand {
+	command := parser.NewCommand("diff", "Compares two log files")
+	config := command.String("c", "config", &argparse.Options{Help: "Configuration file path"})
+	color := command.Flag("", "color", &argparse.Options{Help: "Colorize the differences"})
+	failOn := command.StringList("", "fail-on", &argparse.Options{Help: `Fails if any row matches the rule (e.g. "P95 diff > 20%")`})
+	prev := command.StringPositional(nil)
+	file := command.StringPositional(nil)
+
+	return &DiffCommand{
+		Command:    command,
+		ConfigFile: config,
+		Color:      color,
+		FailOn:     failOn,
+		PrevFile:   prev,
+		LogFile:    file,
 	}
 }
 
@@ -72,6 +153,8 @@ func main() {
 	initCommand := parser.NewCommand("init", "Generates a new akari configuration file")
 	runCommand := NewRunCommand(parser)
 	serveCommand := NewServeCommand(parser)
+	reportCommand := NewReportCommand(parser)
+	diffCommand := NewDiffCommand(parser)
 
 	if err := parser.Parse(os.Args); err != nil {
 		fmt.Print(parser.Usage(err))
@@ -89,10 +172,36 @@ func main() {
 		}
 	} else if runCommand.Command.Happened() {
 		if err := cmd.Run(cmd.RunOptions{
-			ConfigFile: akari.StringOr(*runCommand.ConfigFile, defaultConfigPath),
-			LogFile:    *runCommand.LogFile,
-			GlobalSeed: globalSeed,
-			Writer:     os.Stdout,
+			ConfigFile:  akari.StringOr(*runCommand.ConfigFile, defaultConfigPath),
+			LogFile:     *runCommand.LogFile,
+			Format:      akari.OutputFormat(*runCommand.Format),
+			FailOn:      *runCommand.FailOn,
+			Outliers:    *runCommand.Outliers,
+			Writer:      os.Stdout,
+			ErrorWriter: os.Stderr,
+		}); err != nil {
+			log.Fatal(err)
+		}
+	} else if reportCommand.Command.Happened() {
+		if err := cmd.Report(cmd.ReportOptions{
+			ConfigFile:    akari.StringOr(*reportCommand.ConfigFile, defaultConfigPath),
+			LogFile:       *reportCommand.LogFile,
+			PrevFile:      *reportCommand.PrevFile,
+			OutputFile:    *reportCommand.OutputFile,
+			TemplateFiles: templateFiles,
+			PublicFS:      publicFS,
+		}); err != nil {
+			log.Fatal(err)
+		}
+	} else if diffCommand.Command.Happened() {
+		if err := cmd.Diff(cmd.DiffOptions{
+			ConfigFile:  akari.StringOr(*diffCommand.ConfigFile, defaultConfigPath),
+			PrevFile:    *diffCommand.PrevFile,
+			LogFile:     *diffCommand.LogFile,
+			Color:       *diffCommand.Color,
+			FailOn:      *diffCommand.FailOn,
+			Writer:      os.Stdout,
+			ErrorWriter: os.Stderr,
 		}); err != nil {
 			log.Fatal(err)
 		}
@@ -107,12 +216,24 @@ func main() {
 			port, _ = strconv.Atoi(val)
 		}
 
+		storeDir := *serveCommand.StoreDir
+		if storeDir == "" {
+			if cacheDir, err := os.UserCacheDir(); err == nil {
+				storeDir = filepath.Join(cacheDir, "akari", "snapshots")
+			}
+		}
+		if *serveCommand.NoStore {
+			storeDir = ""
+		}
+
 		if err := cmd.Serve(cmd.ServeOptions{
 			ConfigFile:    akari.StringOr(*serveCommand.ConfigFile, defaultConfigPath),
 			LogDir:        *serveCommand.LogDir,
 			TemplateFiles: templateFiles,
 			PublicFS:      publicFS,
-			HashSeed:      globalSeed,
+			StoreDir:      storeDir,
+			AllowUpload:   *serveCommand.AllowUpload,
+			MaxUploadSize: int64(*serveCommand.MaxUploadSize) << 20,
 			Port:          port,
 			Hostname:      hostName,
 		}); err != nil {
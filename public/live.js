@@ -0,0 +1,43 @@
+// Re-renders the parts of the page when the server notifies the changes
+const watchChanges = (url, selectors, onUpdate) => {
+  const source = new EventSource(url);
+
+  source.addEventListener("change", async () => {
+    const response = await fetch(location.href);
+    if (!response.ok) {
+      return;
+    }
+
+    const next = new DOMParser().parseFromString(
+      await response.text(),
+      "text/html"
+    );
+
+    selectors.forEach((selector) => {
+      const current = document.querySelector(selector);
+      const replacement = next.querySelector(selector);
+      if (!current || !replacement) {
+        return;
+      }
+
+      // do not throw away what the user is typing
+      if (current.contains(document.activeElement) && document.activeElement.matches("input, textarea, select")) {
+        return;
+      }
+
+      // keep the opened details
+      current.querySelectorAll("details[open][data-key]").forEach((details) => {
+        const opened = replacement.querySelector(
+          `details[data-key="${CSS.escape(details.dataset.key)}"]`
+        );
+        if (opened) {
+          opened.open = true;
+        }
+      });
+
+      current.replaceWith(replacement);
+    });
+
+    onUpdate?.();
+  });
+};
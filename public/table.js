@@ -11,7 +11,7 @@ const getHeatmapColor = (value_) => {
   } ${percentage}%, transparent)`;
 };
 
-window.addEventListener("load", () => {
+const colorizeTable = () => {
   const diffIndexes = [];
   const colorize = [];
   const headers = document.querySelectorAll("thead th");
@@ -38,6 +38,13 @@ window.addEventListener("load", () => {
         const value = cell.dataset.value;
 
         if (value !== undefined) {
+          // 有意差のない差分は色をつけずに薄く表示する
+          if (cell.dataset.insignificant) {
+            cell.classList.add("insignificant");
+            Array.from(cells)[index - 1].classList.add("insignificant");
+            return;
+          }
+
           cell.style.backgroundColor = getHeatmapColor(value);
           // diffのカラムの一つ前のカラムも色をつける
           Array.from(cells)[index - 1].style.backgroundColor =
@@ -56,4 +63,163 @@ window.addEventListener("load", () => {
       }
     });
   });
-});
+};
+
+window.addEventListener("load", colorizeTable);
+
+// Sorting, searching and column toggling of the view table. The state is kept in the URL, so that the view can be shared.
+//   sort: the column to sort by, prefixed with "-" for the descending order
+//   q: the text to search in the grouping columns
+//   hide: the comma-separated columns to hide
+const tableState = () => {
+  const params = new URLSearchParams(location.search);
+
+  return {
+    sort: params.get("sort") ?? "",
+    q: params.get("q") ?? "",
+    hide: (params.get("hide") ?? "").split(",").filter((name) => name),
+  };
+};
+
+const saveTableState = (state) => {
+  const params = new URLSearchParams(location.search);
+  const values = { sort: state.sort, q: state.q, hide: state.hide.join(",") };
+  Object.entries(values).forEach(([key, value]) => {
+    if (value) {
+      params.set(key, value);
+    } else {
+      params.delete(key);
+    }
+  });
+
+  history.replaceState(null, "", `${location.pathname}?${params}`);
+};
+
+// raw values are compared as numbers if possible, and missing values come last in either order
+const compareValues = (a, b, descending) => {
+  const missing = (value) => value === undefined || value === "" || value === "<nil>";
+  if (missing(a) || missing(b)) {
+    return missing(a) - missing(b);
+  }
+
+  const x = Number(a);
+  const y = Number(b);
+  const order =
+    !Number.isNaN(x) && !Number.isNaN(y) ? x - y : a.localeCompare(b);
+
+  return descending ? -order : order;
+};
+
+const applyTable = (table) => {
+  const state = tableState();
+  const headers = Array.from(table.querySelectorAll("thead th"));
+  const tbody = table.querySelector("tbody");
+  const rows = Array.from(tbody.children);
+
+  // the server order is restored when the sort is cleared
+  rows.forEach((tr, i) => {
+    if (tr.dataset.index === undefined) {
+      tr.dataset.index = i;
+    }
+  });
+
+  const descending = state.sort.startsWith("-");
+  const sortColumn = descending ? state.sort.slice(1) : state.sort;
+  const sortIndex = headers.findIndex(
+    (th) => th.dataset.column === sortColumn && !th.dataset.diff
+  );
+  headers.forEach((th, i) => {
+    th.classList.toggle("sort-asc", i === sortIndex && !descending);
+    th.classList.toggle("sort-desc", i === sortIndex && descending);
+  });
+
+  rows.sort((a, b) => {
+    // the other row stays at the bottom
+    if (a.dataset.other || b.dataset.other) {
+      return !!a.dataset.other - !!b.dataset.other;
+    }
+    if (sortIndex >= 0) {
+      const order = compareValues(
+        a.children[sortIndex]?.dataset.value,
+        b.children[sortIndex]?.dataset.value,
+        descending
+      );
+      if (order !== 0) {
+        return order;
+      }
+    }
+    return a.dataset.index - b.dataset.index;
+  });
+  tbody.append(...rows);
+
+  const query = state.q.toLowerCase();
+  const groupingIndexes = headers.flatMap((th, i) => (th.dataset.grouping ? [i] : []));
+  rows.forEach((tr) => {
+    const text = groupingIndexes
+      .map((i) => tr.children[i]?.textContent ?? "")
+      .join(" ")
+      .toLowerCase();
+    tr.hidden = query !== "" && (!!tr.dataset.other || !text.includes(query));
+  });
+
+  headers.forEach((th, i) => {
+    const hidden = state.hide.includes(th.dataset.column);
+    th.hidden = hidden;
+    rows.forEach((tr) => {
+      if (tr.children[i]) {
+        tr.children[i].hidden = hidden;
+      }
+    });
+  });
+};
+
+const setupTable = (table, controls) => {
+  const state = tableState();
+  const headers = Array.from(table.querySelectorAll("thead th[data-column]"));
+
+  headers
+    .filter((th) => !th.dataset.diff)
+    .forEach((th) => {
+      th.classList.add("sortable");
+      th.addEventListener("click", () => {
+        // none -> descending -> ascending -> none
+        const state = tableState();
+        const column = th.dataset.column;
+        state.sort =
+          state.sort === `-${column}` ? column : state.sort === column ? "" : `-${column}`;
+        saveTableState(state);
+        applyTable(table);
+      });
+    });
+
+  const search = controls.querySelector("input[type=search]");
+  search.value = state.q;
+  search.addEventListener("input", () => {
+    const state = tableState();
+    state.q = search.value;
+    saveTableState(state);
+    applyTable(table);
+  });
+
+  const columns = controls.querySelector(".table-columns");
+  const names = [...new Set(headers.map((th) => th.dataset.column))];
+  names.forEach((name) => {
+    const label = document.createElement("label");
+    const checkbox = document.createElement("input");
+    checkbox.type = "checkbox";
+    checkbox.checked = !state.hide.includes(name);
+    checkbox.addEventListener("change", () => {
+      const state = tableState();
+      state.hide = checkbox.checked
+        ? state.hide.filter((hidden) => hidden !== name)
+        : [...state.hide, name];
+      saveTableState(state);
+      applyTable(table);
+    });
+
+    label.append(checkbox, name);
+    columns.append(label);
+  });
+
+  applyTable(table);
+};